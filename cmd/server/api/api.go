@@ -1,13 +1,19 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"strings"
 
 	"github.com/go-toschool/sicily"
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/cmd/server/logging"
+	"github.com/go-toschool/sicily/graph/cache"
 	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
 )
 
 // API ...
@@ -43,13 +49,104 @@ func API(ctx *Context, w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		result = ctx.ExecuteQuery(gr.Query, id)
+		logging.SetOperation(r.Context(), operation(gr.Query))
+
+		if !allowed(r.Context(), gr.Query) {
+			http.Error(w, "Insufficient scope", http.StatusForbidden)
+			return
+		}
+
+		if !isMutation(gr.Query) {
+			result = ctx.executeCached(r.Context(), w, gr, id)
+			break
+		}
+
+		// The policy collects the types the mutation makes stale.
+		policy := cache.NewPolicy()
+		mctx := cache.WithPolicy(r.Context(), policy)
+
+		key := idempotencyKey(r, gr)
+		if key != "" && ctx.Idempotency != nil {
+			var ok bool
+			if result, ok = ctx.executeIdempotent(mctx, w, gr, id, key); !ok {
+				return
+			}
+		} else {
+			result = ctx.execute(mctx, gr, id)
+		}
+
+		if ctx.Cache != nil {
+			ctx.Cache.Invalidate(policy.Invalidated())
+		}
+		w.Header().Set(CacheControlHeader, "no-store")
 	default:
 		http.Error(w, "bad content type", http.StatusBadRequest)
 		return
 	}
 
+	for _, e := range result.Errors {
+		if code, ok := e.Extensions["code"].(string); ok {
+			logging.AddErrorCodes(r.Context(), code)
+		}
+	}
+
 	w.Header().Set("Accept-Encoding", "gzip")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(withRequestID(result, logging.RequestID(r.Context())))
+}
+
+// allowed reports whether the caller may run query, services authenticated
+// by an API key and callers authenticated by a JWT need the read scope for
+// queries and the write scope for mutations.
+func allowed(ctx context.Context, query string) bool {
+	scope := firewall.ScopeRead
+	if isMutation(query) {
+		scope = firewall.ScopeWrite
+	}
+
+	if p, ok := firewall.PrincipalFromContext(ctx); ok {
+		return p.HasScope(scope)
+	}
+	if c, ok := firewall.ClaimsFromContext(ctx); ok {
+		return c.HasScope(scope)
+	}
+	return true
+}
+
+// operation describes the first operation of a document for the access log,
+// e.g. "query talks" or "mutation".
+func operation(query string) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return ""
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if op.Name != nil && op.Name.Value != "" {
+			return op.Operation + " " + op.Name.Value
+		}
+		return op.Operation
+	}
+
+	return ""
+}
+
+// withRequestID returns a copy of result with the request id in its
+// extensions, result may be shared through the caches.
+func withRequestID(result *graphql.Result, requestID string) *graphql.Result {
+	if requestID == "" {
+		return result
+	}
+
+	res := *result
+	res.Extensions = make(map[string]interface{}, len(result.Extensions)+1)
+	for k, v := range result.Extensions {
+		res.Extensions[k] = v
+	}
+	res.Extensions["requestId"] = requestID
+	return &res
 }
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-toschool/sicily/cmd/server/expiry"
+	"github.com/go-toschool/sicily/graph/cache"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+)
+
+const (
+	// CacheControlHeader tells clients and proxies how long a response can
+	// be cached, from the cache hints of the fields queried.
+	CacheControlHeader = "Cache-Control"
+	// ResponseCacheHeader is set to HIT on responses served from the
+	// response cache and to MISS on cacheable responses that were executed.
+	ResponseCacheHeader = "X-Cache"
+)
+
+// ResponseCache keeps the results of queries during the max age of their
+// cache hints.
+type ResponseCache interface {
+	// Get returns the result stored under key and its hint, with the max
+	// age left.
+	Get(key string) (*graphql.Result, cache.Hint, bool)
+	// Set stores the result of a query that started executing at started
+	// and resolved types. It is dropped when one of types was invalidated
+	// since, as it may be stale already.
+	Set(key string, result *graphql.Result, hint cache.Hint, types []string, started time.Time)
+	// Invalidate drops the results that resolved any of types.
+	Invalidate(types []string)
+}
+
+type cacheEntry struct {
+	result *graphql.Result
+	hint   cache.Hint
+	types  []string
+}
+
+// MemoryResponseCache is a ResponseCache keeping up to a fixed number of
+// results in memory.
+type MemoryResponseCache struct {
+	now func() time.Time
+
+	mu sync.Mutex
+	// entries hold a *cacheEntry per key.
+	entries     *expiry.Cache
+	invalidated map[string]time.Time
+}
+
+// NewMemoryResponseCache creates a cache keeping up to size results.
+func NewMemoryResponseCache(size int) *MemoryResponseCache {
+	return &MemoryResponseCache{
+		now:         time.Now,
+		entries:     expiry.New(size),
+		invalidated: make(map[string]time.Time),
+	}
+}
+
+// Get implements ResponseCache.
+func (c *MemoryResponseCache) Get(key string) (*graphql.Result, cache.Hint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	v, expires, ok := c.entries.Get(key, now)
+	if !ok {
+		return nil, cache.Hint{}, false
+	}
+
+	e := v.(*cacheEntry)
+	return e.result, cache.Hint{MaxAge: expires.Sub(now), Scope: e.hint.Scope}, true
+}
+
+// Set implements ResponseCache.
+func (c *MemoryResponseCache) Set(key string, result *graphql.Result, hint cache.Hint, types []string, started time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, t := range types {
+		if at, ok := c.invalidated[t]; ok && !at.Before(started) {
+			return
+		}
+	}
+
+	now := c.now()
+	c.entries.Set(key, &cacheEntry{
+		result: result,
+		hint:   hint,
+		types:  types,
+	}, now.Add(hint.MaxAge), now)
+}
+
+// Invalidate implements ResponseCache.
+func (c *MemoryResponseCache) Invalidate(types []string) {
+	if len(types) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	stale := make(map[string]bool, len(types))
+	for _, t := range types {
+		stale[t] = true
+		c.invalidated[t] = now
+	}
+
+	c.entries.Range(func(key string, v interface{}) {
+		for _, t := range v.(*cacheEntry).types {
+			if stale[t] {
+				c.entries.Delete(key)
+				return
+			}
+		}
+	})
+}
+
+// cacheKey identifies a query by its normalized document, its variables
+// and who can see its result: scope is empty for public results and holds
+// the user id for private ones.
+func cacheKey(document string, variables map[string]interface{}, scope string) string {
+	vars, _ := json.Marshal(variables)
+
+	h := sha256.New()
+	h.Write([]byte(document))
+	h.Write([]byte{0})
+	h.Write(vars)
+	h.Write([]byte{0})
+	h.Write([]byte(scope))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// executeCached runs a query, serving it from the response cache when a
+// result visible to its caller is stored, and sets the Cache-Control header from
+// the cache hints of the fields queried. Results with errors are not cached.
+func (c *Context) executeCached(ctx context.Context, w http.ResponseWriter, gr *GraphRequest, userID string) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: gr.Query})
+	if err != nil {
+		// Let the execution report the syntax error.
+		w.Header().Set(CacheControlHeader, cache.Hint{}.CacheControl())
+		return c.execute(ctx, gr, userID)
+	}
+
+	document, _ := printer.Print(doc).(string)
+	publicKey := cacheKey(document, gr.Variables, "")
+	// Keyed by owner, callers authenticated without a user id do not share
+	// their private results.
+	privateKey := cacheKey(document, gr.Variables, owner(ctx, userID))
+
+	if c.Cache != nil {
+		for _, key := range []string{publicKey, privateKey} {
+			if result, hint, ok := c.Cache.Get(key); ok {
+				w.Header().Set(CacheControlHeader, hint.CacheControl())
+				w.Header().Set(ResponseCacheHeader, "HIT")
+				return result
+			}
+		}
+	}
+
+	started := time.Now()
+	policy := cache.NewPolicy()
+	result := c.execute(cache.WithPolicy(ctx, policy), gr, userID)
+
+	hint := policy.Hint()
+	if len(result.Errors) > 0 {
+		hint = cache.Hint{}
+	}
+	w.Header().Set(CacheControlHeader, hint.CacheControl())
+
+	if c.Cache != nil && hint.MaxAge >= time.Second {
+		key := publicKey
+		if hint.Scope == cache.Private {
+			key = privateKey
+		}
+		c.Cache.Set(key, result, hint, policy.Types(), started)
+		w.Header().Set(ResponseCacheHeader, "MISS")
+	}
+
+	return result
+}
@@ -2,13 +2,20 @@ package api
 
 import (
 	"context"
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
 
 	"github.com/go-toschool/palermo/auth"
 	"github.com/go-toschool/sicily"
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/cmd/server/logging"
+	"github.com/go-toschool/sicily/cmd/server/tracing"
+	"github.com/go-toschool/sicily/graph"
 	"github.com/go-toschool/syracuse/citizens"
 	"github.com/graphql-go/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // ContentTypeGraphQL graphql content type.
@@ -19,7 +26,9 @@ const (
 
 // GraphRequest struct to unmarshal query.
 type GraphRequest struct {
-	Query string `json:"query"`
+	Query      string                 `json:"query"`
+	Variables  map[string]interface{} `json:"variables,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 // Context ...
@@ -27,6 +36,30 @@ type Context struct {
 	User    citizens.CitizenshipClient
 	Session auth.AuthServiceClient
 	Schema  graphql.Schema
+
+	// Idempotency stores mutation results by idempotency key, nil disables
+	// idempotency keys.
+	Idempotency IdempotencyStore
+	// Cache stores the results of queries by their cache hints, nil
+	// disables the response cache.
+	Cache ResponseCache
+	// Sessions keeps the sessions validated by palermo, nil disables the
+	// session cache.
+	Sessions *firewall.SessionCache
+	// APIKeys authenticates services by their X-API-Key header, nil
+	// disables API keys.
+	APIKeys firewall.APIKeyStore
+	// JWT verifies the Bearer tokens that are JWTs locally, nil sends every
+	// token to palermo.
+	JWT *firewall.JWTVerifier
+	// CSRF protects the requests authenticated by the access_token cookie,
+	// nil disables it.
+	CSRF *firewall.CSRF
+
+	// Admins are the ids of the users allowed to read the audit log, along
+	// with the users authenticated by a JWT granting AdminRole.
+	Admins    []string
+	AdminRole string
 }
 
 // Handle creates a new bounded Handler with context.
@@ -36,19 +69,87 @@ func (c *Context) Handle(h HandlerFunc) *Handler {
 
 // ExecuteQuery ...
 func (c *Context) ExecuteQuery(query, userID string) *graphql.Result {
-	ctx := context.WithValue(context.Background(), sicily.UserIDKey, userID)
+	return c.execute(context.Background(), &GraphRequest{Query: query}, userID)
+}
+
+// execute runs gr on behalf of userID with ctx as the parent context of the
+// resolvers.
+func (c *Context) execute(ctx context.Context, gr *GraphRequest, userID string) *graphql.Result {
+	ctx, span := tracing.Start(ctx, "graphql.execute", documentAttributes(gr.Query)...)
+	defer span.End()
+
+	ctx = context.WithValue(ctx, sicily.UserIDKey, userID)
+	ctx = graph.WithCaller(ctx, c.caller(ctx, userID))
 	result := graphql.Do(graphql.Params{
-		Schema:        c.Schema,
-		RequestString: query,
-		Context:       ctx,
+		Schema:         c.Schema,
+		RequestString:  gr.Query,
+		VariableValues: gr.Variables,
+		Context:        ctx,
 	})
 	if len(result.Errors) > 0 {
-		fmt.Printf("errors: %v\n", result.Errors)
+		span.SetStatus(codes.Error, result.Errors[0].Message)
+		logging.FromContext(ctx).Debug("graphql errors", "errors", result.Errors)
 	}
 
 	return result
 }
 
+// documentAttributes describes a document on the span of its execution by
+// its operation and a hash, as documents can hold secrets inlined by their
+// clients.
+func documentAttributes(query string) []attribute.KeyValue {
+	sum := sha256.Sum256([]byte(query))
+	attrs := []attribute.KeyValue{
+		attribute.String("graphql.document.sha256", hex.EncodeToString(sum[:])),
+	}
+	if op := operation(query); op != "" {
+		attrs = append(attrs, attribute.String("graphql.operation", op))
+	}
+	return attrs
+}
+
+// owner namespaces the state kept for the caller of a request, like its
+// idempotency keys: the service authenticated by an API key, the session of
+// a user, or the user when authenticated without a session.
+func owner(ctx context.Context, userID string) string {
+	if id, ok := firewall.IdentityFromContext(ctx); ok {
+		switch {
+		case id.Principal != "":
+			return "principal:" + id.Principal
+		case id.SessionID != "":
+			return "session:" + id.SessionID
+		}
+	}
+	return "user:" + userID
+}
+
+// caller describes who runs a request for the audit trail.
+func (c *Context) caller(ctx context.Context, userID string) *graph.Caller {
+	caller := &graph.Caller{
+		UserID:    userID,
+		RequestID: logging.RequestID(ctx),
+	}
+	if id, ok := firewall.IdentityFromContext(ctx); ok {
+		caller.Principal = id.Principal
+		caller.Session = id.Session
+	}
+
+	for _, admin := range c.Admins {
+		if userID != "" && admin == userID {
+			caller.Admin = true
+		}
+	}
+	if claims, ok := firewall.ClaimsFromContext(ctx); ok && c.AdminRole != "" {
+		for _, role := range claims.Roles {
+			if role == c.AdminRole {
+				caller.Admin = true
+			}
+		}
+	}
+
+	return caller
+}
+
 // HandlerFunc function handler signature used by sigiriya application.
 type HandlerFunc func(*Context, http.ResponseWriter, *http.Request)
 
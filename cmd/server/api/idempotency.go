@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	// IdempotencyKeyHeader is the header carrying the idempotency key of a
+	// mutation, it can also be sent as extensions.idempotencyKey.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader is set on responses replayed from the store.
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyKeyExtension = "idempotencyKey"
+)
+
+var (
+	// ErrIdempotencyInProgress is returned when a request using the same key
+	// is still being executed.
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+	// ErrIdempotencyMismatch is returned when a key is reused with a
+	// different request.
+	ErrIdempotencyMismatch = errors.New("idempotency key already used for a different request")
+)
+
+// IdempotencyStore keeps the results of mutations so retried requests are
+// replayed instead of executed twice.
+type IdempotencyStore interface {
+	// Reserve claims key for a request with the given fingerprint. When the
+	// key was used by a completed request with the same fingerprint its
+	// result is returned.
+	Reserve(key, fingerprint string) (*graphql.Result, error)
+	// Complete stores the result of the request holding key.
+	Complete(key string, result *graphql.Result)
+	// Release drops the reservation of a request that failed, so it can be
+	// retried.
+	Release(key string)
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	result      *graphql.Result
+	expires     time.Time
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore keeping results in memory
+// for a fixed time.
+type MemoryIdempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	sweep   time.Time
+}
+
+// NewMemoryIdempotencyStore creates a store keeping results during ttl.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// Reserve implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Reserve(key, fingerprint string) (*graphql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.removeExpired(now)
+
+	e, ok := s.entries[key]
+	if ok && now.Before(e.expires) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, ErrIdempotencyMismatch
+		case e.result == nil:
+			return nil, ErrIdempotencyInProgress
+		default:
+			return e.result, nil
+		}
+	}
+
+	s.entries[key] = &idempotencyEntry{
+		fingerprint: fingerprint,
+		expires:     now.Add(s.ttl),
+	}
+
+	return nil, nil
+}
+
+// Complete implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Complete(key string, result *graphql.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.result = result
+		e.expires = s.now().Add(s.ttl)
+	}
+}
+
+// Release implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// removeExpired drops expired entries at most once per ttl, it must be
+// called holding the lock.
+func (s *MemoryIdempotencyStore) removeExpired(now time.Time) {
+	if now.Before(s.sweep) {
+		return
+	}
+	s.sweep = now.Add(s.ttl)
+
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// idempotencyKey returns the key sent with the request, if any.
+func idempotencyKey(r *http.Request, gr *GraphRequest) string {
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		return key
+	}
+
+	key, _ := gr.Extensions[idempotencyKeyExtension].(string)
+	return key
+}
+
+// isMutation reports whether the document contains a mutation operation.
+func isMutation(query string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok && op.Operation == ast.OperationTypeMutation {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fingerprint(gr *GraphRequest) string {
+	vars, _ := json.Marshal(gr.Variables)
+
+	h := sha256.New()
+	h.Write([]byte(gr.Query))
+	h.Write([]byte{0})
+	h.Write(vars)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// executeIdempotent runs a mutation at most once per caller and key,
+// replaying the stored result for retries. Only results where no mutation
+// returned data are dropped so the mutation can be retried, a mutation that
+// ran but has failing nested fields is replayed with its partial errors.
+func (c *Context) executeIdempotent(ctx context.Context, w http.ResponseWriter, gr *GraphRequest, userID, key string) (*graphql.Result, bool) {
+	storeKey := owner(ctx, userID) + "\x00" + key
+
+	stored, err := c.Idempotency.Reserve(storeKey, fingerprint(gr))
+	switch err {
+	case nil:
+	case ErrIdempotencyMismatch:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return nil, false
+	case ErrIdempotencyInProgress:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return nil, false
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if stored != nil {
+		w.Header().Set(IdempotencyReplayedHeader, "true")
+		return stored, true
+	}
+
+	result := c.execute(ctx, gr, userID)
+	if !executed(result) {
+		c.Idempotency.Release(storeKey)
+	} else {
+		c.Idempotency.Complete(storeKey, result)
+	}
+
+	return result, true
+}
+
+// executed reports whether a mutation of result ran, i.e. a top-level field
+// returned data.
+func executed(result *graphql.Result) bool {
+	data, ok := result.Data.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, v := range data {
+		if v != nil {
+			return true
+		}
+	}
+	return false
+}
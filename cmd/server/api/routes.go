@@ -16,8 +16,16 @@ func Routes(ctx *Context) *mux.Router {
 	r := mux.NewRouter()
 
 	firewall := firewall.NewAuth(ctx.Session)
+	firewall.Sessions = ctx.Sessions
+	firewall.APIKeys = ctx.APIKeys
+	firewall.JWT = ctx.JWT
+	firewall.CSRF = ctx.CSRF
 	api := ctx.Handle(API)
 	r.HandleFunc("/graphql", firewall.CheckCorsAndToken(api))
+	r.HandleFunc("/logout", firewall.Logout)
+	if ctx.CSRF != nil {
+		r.HandleFunc("/csrf", ctx.CSRF.Token)
+	}
 
 	return r
 }
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-toschool/sicily/cmd/server/backend"
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/cmd/server/logging"
+	"github.com/go-toschool/sicily/cmd/server/tracing"
+	"github.com/urfave/negroni"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// paths are the endpoints served by Routes.
+var paths = []string{"/graphql", "/logout", "/csrf"}
+
+// Mount serves the routes of ctx on mux.
+func Mount(mux *http.ServeMux, ctx *Context) {
+	routes := Routes(ctx)
+	for _, path := range paths {
+		mux.Handle(path, routes)
+	}
+}
+
+// Middleware wraps h with the middleware every request of the gateway goes
+// through: request logging, panic recovery and tracing.
+func Middleware(logger *slog.Logger, h http.Handler) http.Handler {
+	n := negroni.New(logging.Middleware(logger), negroni.NewRecovery(), tracing.Middleware())
+	n.UseHandler(h)
+	return n
+}
+
+// Dial connects to the service at target through b, the calls carry the
+// request id and the identity of their caller as configured by forward.
+// opts are added to the options of the gateway, e.g. a dialer.
+func Dial(b *backend.Backend, forward firewall.ForwardOptions, target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithChainUnaryInterceptor(
+			logging.UnaryClientInterceptor,
+			firewall.ForwardIdentity(b.Name(), forward),
+		),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}, opts...)
+	return b.Dial(target, opts...)
+}
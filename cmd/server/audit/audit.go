@@ -0,0 +1,290 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/go-toschool/sicily/graph"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var writeFailures = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "sicily_audit_write_failures_total",
+	Help: "Audit entries that could not be written, the mutations they record ran anyway.",
+})
+
+func init() {
+	prometheus.MustRegister(writeFailures)
+}
+
+// failed counts err as a lost entry.
+func failed(err error) error {
+	writeFailures.Inc()
+	return err
+}
+
+// WriterSink writes the audit entries as JSON lines to w, and keeps the
+// last ones in memory to read them back.
+type WriterSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	keep   int
+	recent []*graph.AuditEntry
+}
+
+// NewWriterSink creates a sink writing to w and keeping the last keep
+// entries in memory.
+func NewWriterSink(w io.Writer, keep int) *WriterSink {
+	return &WriterSink{w: w, keep: keep}
+}
+
+// NewStdoutSink creates a sink writing to the standard output, for
+// deployments shipping the output of the process.
+func NewStdoutSink(keep int) *WriterSink {
+	return NewWriterSink(os.Stdout, keep)
+}
+
+// Write implements graph.AuditSink.
+func (s *WriterSink) Write(ctx context.Context, e *graph.AuditEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return failed(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.keep > 0 {
+		if len(s.recent) == s.keep {
+			s.recent = s.recent[1:]
+		}
+		s.recent = append(s.recent, e)
+	}
+
+	if _, err := s.w.Write(append(b, '\n')); err != nil {
+		return failed(fmt.Errorf("audit: %v", err))
+	}
+	return nil
+}
+
+// Recent implements graph.AuditReader, it only knows the entries written
+// by this process since it started.
+func (s *WriterSink) Recent(ctx context.Context, limit int) ([]*graph.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]*graph.AuditEntry, 0, limit)
+	for i := len(s.recent) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, s.recent[i])
+	}
+	return entries, nil
+}
+
+// FileSink appends the audit entries as JSON lines to a file, synced after
+// every entry. Once the file reaches maxSize it is renamed with a ".1"
+// suffix, shifting the older files, and only maxFiles rotated files are kept.
+type FileSink struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewFileSink opens, or creates, the audit file at path.
+func NewFileSink(path string, maxSize int64, maxFiles int) (*FileSink, error) {
+	s := &FileSink{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Write implements graph.AuditSink.
+func (s *FileSink) Write(ctx context.Context, e *graph.AuditEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return failed(err)
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A rotation failed to reopen the file, try again.
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			return failed(err)
+		}
+	}
+
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(b)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return failed(err)
+		}
+	}
+
+	n, err := s.f.Write(b)
+	s.size += int64(n)
+	if err == nil {
+		err = s.f.Sync()
+	}
+	if err != nil {
+		return failed(fmt.Errorf("audit: %v", err))
+	}
+	return nil
+}
+
+// Recent implements graph.AuditReader, it reads the current file and the
+// rotated ones until limit entries are found. The files are opened holding
+// the lock, so a rotation can not shift them while they are read, and read
+// without it, so writes do not wait.
+func (s *FileSink) Recent(ctx context.Context, limit int) ([]*graph.AuditEntry, error) {
+	files, err := s.openAll()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	entries := make([]*graph.AuditEntry, 0, limit)
+	for _, f := range files {
+		if len(entries) == limit {
+			break
+		}
+		tail, err := readTail(f, limit-len(entries))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, tail...)
+	}
+
+	return entries, nil
+}
+
+// openAll opens the current file and the rotated ones, newest first.
+func (s *FileSink) openAll() ([]*os.File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files := make([]*os.File, 0, s.maxFiles+1)
+	for i := 0; i <= s.maxFiles; i++ {
+		f, err := os.Open(s.name(i))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, fmt.Errorf("audit: %v", err)
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// Close closes the current file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+	return s.f.Close()
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("audit: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("audit: %v", err)
+	}
+
+	s.f = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shifts the files and reopens the current one, it must be called
+// holding the lock. The current file is reopened whatever happens, when the
+// files can not be shifted entries are appended to it past maxSize.
+func (s *FileSink) rotate() error {
+	s.f.Close()
+	s.f = nil
+	if err := s.shift(); err != nil {
+		slog.Error("audit: rotation failed, appending to the current file", "error", err)
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) shift() error {
+	if s.maxFiles == 0 {
+		return os.Remove(s.path)
+	}
+
+	os.Remove(s.name(s.maxFiles))
+	for i := s.maxFiles - 1; i >= 0; i-- {
+		if err := os.Rename(s.name(i), s.name(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// name returns the name of the file rotated i times, 0 being the current one.
+func (s *FileSink) name(i int) string {
+	if i == 0 {
+		return s.path
+	}
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+// readTail returns the last limit entries of r, newest first.
+func readTail(r io.Reader, limit int) ([]*graph.AuditEntry, error) {
+	ring := make([]*graph.AuditEntry, 0, limit)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		e := &graph.AuditEntry{}
+		if err := json.Unmarshal(sc.Bytes(), e); err != nil {
+			continue
+		}
+		if len(ring) == limit {
+			ring = append(ring[1:], e)
+			continue
+		}
+		ring = append(ring, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("audit: %v", err)
+	}
+
+	for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+		ring[i], ring[j] = ring[j], ring[i]
+	}
+	return ring, nil
+}
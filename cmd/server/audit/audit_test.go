@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-toschool/sicily/graph"
+)
+
+// entry returns the i-th entry, all of them having the same size.
+func entry(i int) *graph.AuditEntry {
+	return &graph.AuditEntry{
+		Time:      time.Unix(1552586400, 0).UTC(),
+		RequestID: fmt.Sprintf("r-%02d", i),
+		Mutation:  "cancelTalk",
+		Outcome:   "ok",
+	}
+}
+
+// lineSize is the size of an entry in the file.
+func lineSize(t *testing.T) int64 {
+	t.Helper()
+
+	b, err := json.Marshal(entry(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int64(len(b) + 1)
+}
+
+// requestIDs returns the request ids of the entries of the file at path.
+func requestIDs(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var ids []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		e := &graph.AuditEntry{}
+		if err := json.Unmarshal(sc.Bytes(), e); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.RequestID)
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("%s = %v, want %v", what, got, want)
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	s, err := NewFileSink(path, 3*lineSize(t), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := s.Write(context.Background(), entry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each file holds three entries, the oldest ones were dropped with the
+	// third rotated file.
+	assertIDs(t, path, requestIDs(t, path), "r-09")
+	assertIDs(t, path+".1", requestIDs(t, path+".1"), "r-06", "r-07", "r-08")
+	assertIDs(t, path+".2", requestIDs(t, path+".2"), "r-03", "r-04", "r-05")
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("%s.3 kept: %v", path, err)
+	}
+
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		{1, []string{"r-09"}},
+		{5, []string{"r-09", "r-08", "r-07", "r-06", "r-05"}},
+		{100, []string{"r-09", "r-08", "r-07", "r-06", "r-05", "r-04", "r-03"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.limit), func(t *testing.T) {
+			entries, err := s.Recent(context.Background(), tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(entries))
+			for i, e := range entries {
+				ids[i] = e.RequestID
+			}
+			assertIDs(t, "Recent()", ids, tt.want...)
+		})
+	}
+}
+
+func TestFileSinkReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	write := func(i int) {
+		t.Helper()
+
+		s, err := NewFileSink(path, 2*lineSize(t), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if err := s.Write(context.Background(), entry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The size of the existing file counts, and without rotated files to
+	// keep a full one is dropped.
+	write(0)
+	write(1)
+	assertIDs(t, path, requestIDs(t, path), "r-00", "r-01")
+	write(2)
+	assertIDs(t, path, requestIDs(t, path), "r-02")
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Fatalf("%s.1 kept: %v", path, err)
+	}
+}
@@ -0,0 +1,286 @@
+// Package backend makes the calls to the gRPC services resilient: every
+// attempt gets a timeout, idempotent methods are retried with jittered
+// backoff and a circuit breaker per service fails calls fast while the
+// service is down.
+package backend
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/go-toschool/sicily/graph"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"google.golang.org/grpc/status"
+)
+
+// Options configures the calls to one service.
+type Options struct {
+	// Timeout bounds each attempt, 0 disables it.
+	Timeout time.Duration
+	// Retries is the number of extra attempts of idempotent methods.
+	Retries int
+	// Backoff is the base delay between attempts, doubled on each retry
+	// and jittered.
+	Backoff time.Duration
+	// FailureThreshold is the number of consecutive failures opening the
+	// breaker, 0 disables the breaker.
+	FailureThreshold int
+	// Cooldown is how long the breaker stays open before a trial call.
+	Cooldown time.Duration
+	// Balancing is the policy spreading the calls over the addresses of
+	// the service: RoundRobin or LeastRequest.
+	Balancing string
+	// Keepalive pings idle connections to detect dead addresses, a zero
+	// Time disables it.
+	Keepalive keepalive.ClientParameters
+}
+
+// DefaultOptions are the options used by the gateway unless configured.
+var DefaultOptions = Options{
+	Timeout:          2 * time.Second,
+	Retries:          2,
+	Backoff:          50 * time.Millisecond,
+	FailureThreshold: 5,
+	Cooldown:         10 * time.Second,
+	Balancing:        RoundRobin,
+	// grpc-go servers refuse pings more frequent than every 5 minutes
+	// unless their EnforcementPolicy allows them.
+	Keepalive: keepalive.ClientParameters{
+		Time:    5 * time.Minute,
+		Timeout: 20 * time.Second,
+	},
+}
+
+// Backend wraps the connection to one service.
+type Backend struct {
+	name      string
+	opts      Options
+	breaker   *breaker
+	endpoints *endpoints
+}
+
+// New returns the backend of the service called name.
+func New(name string, opts Options) *Backend {
+	breakerState.WithLabelValues(name).Set(float64(Closed))
+	b := &Backend{
+		name: name,
+		opts: opts,
+		breaker: newBreaker(opts.FailureThreshold, opts.Cooldown, func(s State) {
+			breakerState.WithLabelValues(name).Set(float64(s))
+		}),
+		endpoints: &endpoints{states: make(map[string]connectivity.State)},
+	}
+	backends.Store(name, b)
+	return b
+}
+
+// Name returns the name of the service.
+func (b *Backend) Name() string {
+	return b.name
+}
+
+// State returns the state of the breaker of the service.
+func (b *Backend) State() State {
+	return b.breaker.current()
+}
+
+// Status implements healthz.Component, a service is unhealthy while its
+// breaker is open or while every one of its addresses fails.
+func (b *Backend) Status() (string, bool) {
+	s := b.State()
+	_, failing := b.endpoints.snapshot()
+	return s.String(), s != Open && !failing
+}
+
+// Ready implements healthz.Ready, a service is ready while one of its
+// addresses is connected.
+func (b *Backend) Ready() bool {
+	return b.endpoints.ready()
+}
+
+// Endpoints implements healthz.Endpoints, it returns the connectivity state
+// of each address of the service, e.g. READY or TRANSIENT_FAILURE.
+func (b *Backend) Endpoints() map[string]string {
+	states, _ := b.endpoints.snapshot()
+	return states
+}
+
+// Dial connects to the service through the backend interceptor, balancing
+// the calls over its addresses. target is either a comma separated list of
+// addresses, e.g. "10.0.0.1:8004,10.0.0.2:8004", or a gRPC target with a
+// scheme, e.g. "dns:///plato:8004".
+func (b *Backend) Dial(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	cfg, err := serviceConfig(b.name, b.opts.Balancing)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.Contains(target, "://") {
+		r := manual.NewBuilderWithScheme("sicily-" + b.name)
+		state := resolver.State{}
+		for _, addr := range strings.Split(target, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+			}
+		}
+		if len(state.Addresses) == 0 {
+			return nil, fmt.Errorf("backend: %s: no address", b.name)
+		}
+		r.InitialState(state)
+
+		target = r.Scheme() + ":///" + b.name
+		opts = append(opts, grpc.WithResolvers(r))
+	}
+
+	// An idle channel closes its connections, the service would not be
+	// ready again until the next call.
+	opts = append(opts,
+		grpc.WithDefaultServiceConfig(cfg),
+		grpc.WithChainUnaryInterceptor(b.Intercept),
+		grpc.WithIdleTimeout(0),
+	)
+	if b.opts.Keepalive.Time > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(b.opts.Keepalive))
+	}
+	return grpc.Dial(target, opts...)
+}
+
+// Intercept is a grpc.UnaryClientInterceptor applying the options of b.
+func (b *Backend) Intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	attempts := 1
+	if idempotent(method) {
+		attempts += b.opts.Retries
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if b.wait(ctx, attempt) != nil {
+				break
+			}
+			retriesTotal.WithLabelValues(b.name, methodName(method)).Inc()
+		}
+
+		if !b.breaker.allow() {
+			return &Error{
+				Service: b.name,
+				err:     status.Errorf(codes.Unavailable, "%s is unavailable", b.name),
+			}
+		}
+
+		err = b.attempt(ctx, method, req, reply, cc, invoker, opts...)
+		b.breaker.done(failure(err))
+		callsTotal.WithLabelValues(b.name, methodName(method), status.Code(err).String()).Inc()
+
+		if !retryable(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		return &Error{Service: b.name, err: err}
+	}
+	return nil
+}
+
+func (b *Backend) attempt(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if b.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.opts.Timeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// wait sleeps a random time up to Backoff * 2^(attempt-1), returning early
+// when ctx is done.
+func (b *Backend) wait(ctx context.Context, attempt int) error {
+	max := b.opts.Backoff << uint(attempt-1)
+	if max <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(time.Duration(rand.Int63n(int64(max))))
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Error is returned by the calls to a service that failed, or that were not
+// made while its breaker is open with the Unavailable code.
+type Error struct {
+	Service string
+	err     error
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":    graph.ErrorCode(e.err),
+		"service": e.Service,
+	}
+}
+
+// Unwrap returns the error of the call, so errors.Is and errors.As reach
+// it through the service it came from.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// GRPCStatus lets status.Code report the code of the call.
+func (e *Error) GRPCStatus() *status.Status {
+	return status.Convert(e.err)
+}
+
+// idempotent reports whether method can be retried safely.
+func idempotent(method string) bool {
+	switch methodName(method) {
+	case "Get", "Select":
+		return true
+	default:
+		return false
+	}
+}
+
+// methodName returns the method of a full gRPC method "/pkg.Service/Method".
+func methodName(method string) string {
+	return method[strings.LastIndex(method, "/")+1:]
+}
+
+// retryable reports whether err may go away by calling again.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// failure reports whether err tells the service is unhealthy, as opposed to
+// a request being rejected.
+func failure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
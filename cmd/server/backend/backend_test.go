@@ -0,0 +1,230 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeInvoker answers the calls with errs in turn, then with the last one.
+type fakeInvoker struct {
+	errs  []error
+	calls int
+	// deadlines are the time left to each attempt.
+	deadlines []time.Duration
+}
+
+func (f *fakeInvoker) invoke(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		f.deadlines = append(f.deadlines, time.Until(deadline))
+	}
+
+	err := f.errs[len(f.errs)-1]
+	if f.calls < len(f.errs) {
+		err = f.errs[f.calls]
+	}
+	f.calls++
+
+	if status.Code(err) == codes.DeadlineExceeded {
+		<-ctx.Done()
+	}
+	return err
+}
+
+func TestInterceptRetries(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	tests := []struct {
+		name   string
+		method string
+		errs   []error
+		calls  int
+		code   codes.Code
+	}{
+		{"Get retried", "/talks.Talking/Get", []error{unavailable}, 3, codes.Unavailable},
+		{"Select retried", "/talks.Talking/Select", []error{unavailable}, 3, codes.Unavailable},
+		{"Get recovers", "/talks.Talking/Get", []error{unavailable, nil}, 2, codes.OK},
+		{"Create not retried", "/talks.Talking/Create", []error{unavailable}, 1, codes.Unavailable},
+		{"Update not retried", "/talks.Talking/Update", []error{unavailable}, 1, codes.Unavailable},
+		{"Delete not retried", "/talks.Talking/Delete", []error{unavailable}, 1, codes.Unavailable},
+		{"not found not retried", "/talks.Talking/Get", []error{status.Error(codes.NotFound, "no talk")}, 1, codes.NotFound},
+		{"exhausted retried", "/talks.Talking/Select", []error{status.Error(codes.ResourceExhausted, "busy")}, 3, codes.ResourceExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("test", Options{Retries: 2})
+			f := &fakeInvoker{errs: tt.errs}
+
+			err := b.Intercept(context.Background(), tt.method, nil, nil, nil, f.invoke)
+			if f.calls != tt.calls {
+				t.Fatalf("invoked %d times, want %d", f.calls, tt.calls)
+			}
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("status.Code() = %s, want %s", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestInterceptTimeout(t *testing.T) {
+	timeout := 20 * time.Millisecond
+	b := New("test", Options{Timeout: timeout, Retries: 2})
+	f := &fakeInvoker{errs: []error{status.Error(codes.DeadlineExceeded, "too slow")}}
+
+	start := time.Now()
+	err := b.Intercept(context.Background(), "/talks.Talking/Get", nil, nil, nil, f.invoke)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("error = %v, want DeadlineExceeded", err)
+	}
+
+	// Each attempt gets the whole timeout, not what is left of the first.
+	if f.calls != 3 {
+		t.Fatalf("invoked %d times, want 3", f.calls)
+	}
+	for i, left := range f.deadlines {
+		if left <= 0 || left > timeout {
+			t.Fatalf("attempt %d had %s, want up to %s", i, left, timeout)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 3*timeout {
+		t.Fatalf("attempts took %s, want at least %s", elapsed, 3*timeout)
+	}
+
+	// The deadline of the caller is kept when shorter.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout/4)
+	defer cancel()
+	f = &fakeInvoker{errs: []error{nil}}
+	if err := b.Intercept(ctx, "/talks.Talking/Get", nil, nil, nil, f.invoke); err != nil {
+		t.Fatal(err)
+	}
+	if f.deadlines[0] > timeout/4 {
+		t.Fatalf("attempt had %s, want up to %s", f.deadlines[0], timeout/4)
+	}
+}
+
+func TestInterceptBreaker(t *testing.T) {
+	now := time.Unix(1552586400, 0)
+	b := New("test", Options{FailureThreshold: 2, Cooldown: 10 * time.Second})
+	b.breaker.now = func() time.Time { return now }
+
+	call := func(err error) (error, int) {
+		f := &fakeInvoker{errs: []error{err}}
+		return b.Intercept(context.Background(), "/talks.Talking/Create", nil, nil, nil, f.invoke), f.calls
+	}
+	assertState := func(want State) {
+		t.Helper()
+		if got := b.State(); got != want {
+			t.Fatalf("state = %s, want %s", got, want)
+		}
+	}
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	// Rejected requests do not count as failures.
+	call(status.Error(codes.InvalidArgument, "invalid"))
+	call(status.Error(codes.InvalidArgument, "invalid"))
+	assertState(Closed)
+
+	// A success resets the count.
+	call(unavailable)
+	call(nil)
+	call(unavailable)
+	assertState(Closed)
+
+	call(unavailable)
+	assertState(Open)
+
+	err, calls := call(nil)
+	if calls != 0 || status.Code(err) != codes.Unavailable {
+		t.Fatalf("open breaker invoked %d times, error %v", calls, err)
+	}
+
+	// After the cooldown one trial call goes through, its failure opens the
+	// breaker again for another cooldown.
+	now = now.Add(10 * time.Second)
+	if _, calls := call(unavailable); calls != 1 {
+		t.Fatalf("trial invoked %d times", calls)
+	}
+	assertState(Open)
+	if _, calls := call(nil); calls != 0 {
+		t.Fatal("breaker let a call through before the cooldown")
+	}
+
+	now = now.Add(10 * time.Second)
+	if err, calls := call(nil); calls != 1 || err != nil {
+		t.Fatalf("trial invoked %d times, error %v", calls, err)
+	}
+	assertState(Closed)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	now := time.Unix(1552586400, 0)
+	var states []State
+	br := newBreaker(1, time.Second, func(s State) { states = append(states, s) })
+	br.now = func() time.Time { return now }
+
+	br.allow()
+	br.done(true)
+	now = now.Add(time.Second)
+
+	// Only one trial call at a time while half-open.
+	if !br.allow() {
+		t.Fatal("no trial call after the cooldown")
+	}
+	if br.current() != HalfOpen {
+		t.Fatalf("state = %s, want half-open", br.current())
+	}
+	if br.allow() {
+		t.Fatal("second call let through during the trial")
+	}
+	br.done(false)
+
+	want := []State{Open, HalfOpen, Closed}
+	if len(states) != len(want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	b := New("plato", Options{})
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"status", status.Error(codes.NotFound, "no talk"), codes.NotFound},
+		{"other", errors.New("boom"), codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeInvoker{errs: []error{tt.err}}
+			err := b.Intercept(context.Background(), "/talks.Talking/Create", nil, nil, nil, f.invoke)
+
+			var e *Error
+			if !errors.As(err, &e) || e.Service != "plato" {
+				t.Fatalf("error = %#v, want a plato *Error", err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("errors.Is(%v, %v) = false", err, tt.err)
+			}
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("status.Code() = %s, want %s", code, tt.code)
+			}
+			if s, ok := status.FromError(err); !ok || s.Code() != tt.code {
+				t.Fatalf("status.FromError() = %v, %v", s, ok)
+			}
+		})
+	}
+}
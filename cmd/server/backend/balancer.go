@@ -0,0 +1,222 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/leastrequest"
+	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+// Balancing policies spreading the calls over the addresses of a service.
+const (
+	RoundRobin   = "round_robin"
+	LeastRequest = "least_request"
+)
+
+var policies = map[string]string{
+	RoundRobin:   roundrobin.Name,
+	LeastRequest: leastrequest.Name,
+}
+
+// balancerName is the gRPC balancer wrapping the policy of a service to
+// track the state of each of its endpoints.
+const balancerName = "sicily_backend"
+
+// backends are the backends by service, for the balancers to report to.
+var backends sync.Map
+
+func init() {
+	balancer.Register(builder{})
+}
+
+// endpoints holds the connectivity state of the addresses of a service.
+type endpoints struct {
+	mu     sync.Mutex
+	states map[string]connectivity.State
+}
+
+func (e *endpoints) set(addr string, s connectivity.State) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if s == connectivity.Shutdown {
+		delete(e.states, addr)
+		return
+	}
+	e.states[addr] = s
+}
+
+// snapshot returns the state of each endpoint and whether all of them are
+// failing.
+func (e *endpoints) snapshot() (map[string]string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	states := make(map[string]string, len(e.states))
+	failing := len(e.states) > 0
+	for addr, s := range e.states {
+		states[addr] = s.String()
+		if s != connectivity.TransientFailure {
+			failing = false
+		}
+	}
+	return states, failing
+}
+
+// ready reports whether one of the endpoints is connected.
+func (e *endpoints) ready() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range e.states {
+		if s == connectivity.Ready {
+			return true
+		}
+	}
+	return false
+}
+
+type balancerConfig struct {
+	serviceconfig.LoadBalancingConfig `json:"-"`
+
+	Service string `json:"service"`
+	Policy  string `json:"policy"`
+
+	child serviceconfig.LoadBalancingConfig
+}
+
+// serviceConfig returns the gRPC service config balancing the calls to the
+// service called name with policy.
+func serviceConfig(name, policy string) (string, error) {
+	if _, ok := policies[policy]; !ok {
+		return "", fmt.Errorf("backend: unknown balancing policy %q", policy)
+	}
+
+	cfg, err := json.Marshal(map[string]interface{}{
+		"loadBalancingConfig": []interface{}{
+			map[string]interface{}{
+				balancerName: map[string]string{"service": name, "policy": policy},
+			},
+		},
+	})
+	return string(cfg), err
+}
+
+type builder struct{}
+
+func (builder) Name() string {
+	return balancerName
+}
+
+func (builder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	return &trackingBalancer{cc: cc, opts: opts}
+}
+
+func (builder) ParseConfig(s json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	cfg := &balancerConfig{}
+	if err := json.Unmarshal(s, cfg); err != nil {
+		return nil, fmt.Errorf("backend: balancer config: %v", err)
+	}
+
+	name, ok := policies[cfg.Policy]
+	if !ok {
+		return nil, fmt.Errorf("backend: unknown balancing policy %q", cfg.Policy)
+	}
+	if p, ok := balancer.Get(name).(balancer.ConfigParser); ok {
+		child, err := p.ParseConfig(json.RawMessage("{}"))
+		if err != nil {
+			return nil, err
+		}
+		cfg.child = child
+	}
+
+	return cfg, nil
+}
+
+// trackingBalancer delegates to the balancer of the configured policy,
+// recording the state of the connections it opens.
+type trackingBalancer struct {
+	cc    balancer.ClientConn
+	opts  balancer.BuildOptions
+	child balancer.Balancer
+}
+
+func (b *trackingBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	cfg, ok := s.BalancerConfig.(*balancerConfig)
+	if !ok {
+		return balancer.ErrBadResolverState
+	}
+
+	if b.child == nil {
+		cc := b.cc
+		if v, ok := backends.Load(cfg.Service); ok {
+			cc = &trackingClientConn{ClientConn: b.cc, endpoints: v.(*Backend).endpoints}
+		}
+		b.child = balancer.Get(policies[cfg.Policy]).Build(cc, b.opts)
+	}
+
+	s.BalancerConfig = cfg.child
+	return b.child.UpdateClientConnState(s)
+}
+
+func (b *trackingBalancer) ResolverError(err error) {
+	if b.child != nil {
+		b.child.ResolverError(err)
+	}
+}
+
+func (b *trackingBalancer) UpdateSubConnState(sc balancer.SubConn, s balancer.SubConnState) {
+	if b.child != nil {
+		b.child.UpdateSubConnState(sc, s)
+	}
+}
+
+func (b *trackingBalancer) ExitIdle() {
+	if e, ok := b.child.(balancer.ExitIdler); ok {
+		e.ExitIdle()
+	}
+}
+
+func (b *trackingBalancer) Close() {
+	if b.child != nil {
+		b.child.Close()
+	}
+}
+
+type trackingClientConn struct {
+	balancer.ClientConn
+	endpoints *endpoints
+}
+
+func (cc *trackingClientConn) NewSubConn(addrs []resolver.Address, opts balancer.NewSubConnOptions) (balancer.SubConn, error) {
+	names := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		names = append(names, a.Addr)
+	}
+	sort.Strings(names)
+	addr := fmt.Sprint(names)
+	if len(names) == 1 {
+		addr = names[0]
+	}
+
+	listener := opts.StateListener
+	opts.StateListener = func(s balancer.SubConnState) {
+		cc.endpoints.set(addr, s.ConnectivityState)
+		if listener != nil {
+			listener(s)
+		}
+	}
+
+	sc, err := cc.ClientConn.NewSubConn(addrs, opts)
+	if err == nil {
+		cc.endpoints.set(addr, connectivity.Idle)
+	}
+	return sc, err
+}
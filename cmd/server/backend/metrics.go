@@ -0,0 +1,26 @@
+package backend
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	callsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sicily_backend_calls_total",
+		Help: "Calls to the backend services by method and gRPC code, retries included.",
+	}, []string{"backend", "method", "code"})
+
+	retriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sicily_backend_retries_total",
+		Help: "Calls to the backend services retried after a failure.",
+	}, []string{"backend", "method"})
+
+	breakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sicily_backend_circuit_state",
+		Help: "State of the circuit breaker of each backend: 0 closed, 1 half-open, 2 open.",
+	}, []string{"backend"})
+)
+
+func init() {
+	prometheus.MustRegister(callsTotal, retriesTotal, breakerState)
+}
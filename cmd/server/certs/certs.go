@@ -0,0 +1,128 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Options describes how clients are allowed to connect to the gateway.
+type Options struct {
+	MinVersion        string
+	ClientCAFile      string
+	RequireClientCert bool
+}
+
+// Reloader keeps a certificate pair loaded from disk and swaps it on Reload,
+// watch.Files reloads it whenever the files change or the process receives
+// SIGHUP.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewReloader loads the certificate pair and returns a Reloader serving it.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload reads the certificate pair from disk, keeping the previous one if the
+// new files cannot be loaded.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("certs: load key pair: %v", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+
+	return nil
+}
+
+// GetCertificate returns the current certificate, it is meant to be used as
+// tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// ModTime returns the last modification of the certificate pair.
+func (r *Reloader) ModTime() (time.Time, error) {
+	var last time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("certs: %v", err)
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+
+	return last, nil
+}
+
+// Config builds a tls.Config serving certificates from r.
+func Config(r *Reloader, opts Options) (*tls.Config, error) {
+	minVersion, ok := tlsVersions[opts.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("certs: unsupported TLS version %q", opts.MinVersion)
+	}
+
+	cfg := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: r.GetCertificate,
+	}
+
+	if opts.ClientCAFile == "" {
+		if opts.RequireClientCert {
+			return nil, errors.New("certs: client certificates required but no client CA configured")
+		}
+		return cfg, nil
+	}
+
+	pem, err := ioutil.ReadFile(opts.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("certs: read client CA: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("certs: no certificates found in client CA file")
+	}
+
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	if opts.RequireClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
@@ -10,7 +10,8 @@ func (ac *AuthCors) Check(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Content-Length, Accept-Encoding")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Content-Length, Accept-Encoding, Idempotency-Key, X-Request-ID, X-API-Key, X-CSRF-Token, X-Requested-With")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-CSRF-Token")
 		if r.Method == "OPTIONS" {
 			w.Header().Set("Access-Control-Max-Age", "86400")
 			w.WriteHeader(http.StatusOK)
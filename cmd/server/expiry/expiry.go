@@ -0,0 +1,116 @@
+// Package expiry keeps values for a limited time in a cache of fixed size,
+// dropping the entries expiring first when it is full.
+package expiry
+
+import (
+	"container/heap"
+	"time"
+)
+
+type item struct {
+	key     string
+	value   interface{}
+	expires time.Time
+	index   int
+}
+
+// queue is a heap of items ordered by expiry.
+type queue []*item
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].expires.Before(q[j].expires) }
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x interface{}) {
+	it := x.(*item)
+	it.index = len(*q)
+	*q = append(*q, it)
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return it
+}
+
+// Cache holds up to a fixed number of values until they expire. It is not
+// safe for concurrent use, callers guard it with their own lock.
+type Cache struct {
+	size  int
+	items map[string]*item
+	queue queue
+}
+
+// New creates a cache holding up to size values.
+func New(size int) *Cache {
+	return &Cache{
+		size:  size,
+		items: make(map[string]*item),
+	}
+}
+
+// Get returns the value stored under key and when it expires, expired
+// values are dropped.
+func (c *Cache) Get(key string, now time.Time) (interface{}, time.Time, bool) {
+	it, ok := c.items[key]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	if !now.Before(it.expires) {
+		c.remove(it)
+		return nil, time.Time{}, false
+	}
+	return it.value, it.expires, true
+}
+
+// Set stores value under key until expires. The expired values are dropped
+// and, when the cache is still full, the one expiring first.
+func (c *Cache) Set(key string, value interface{}, expires, now time.Time) {
+	if it, ok := c.items[key]; ok {
+		it.value, it.expires = value, expires
+		heap.Fix(&c.queue, it.index)
+		return
+	}
+
+	for len(c.queue) > 0 && !now.Before(c.queue[0].expires) {
+		c.remove(c.queue[0])
+	}
+	if len(c.queue) > 0 && len(c.queue) >= c.size {
+		c.remove(c.queue[0])
+	}
+
+	it := &item{key: key, value: value, expires: expires}
+	heap.Push(&c.queue, it)
+	c.items[key] = it
+}
+
+// Delete drops the value stored under key.
+func (c *Cache) Delete(key string) {
+	if it, ok := c.items[key]; ok {
+		c.remove(it)
+	}
+}
+
+// Range calls f for each value, f may delete the values of the cache.
+func (c *Cache) Range(f func(key string, value interface{})) {
+	for key, it := range c.items {
+		f(key, it.value)
+	}
+}
+
+// Len returns the number of values stored, expired or not.
+func (c *Cache) Len() int {
+	return len(c.items)
+}
+
+func (c *Cache) remove(it *item) {
+	heap.Remove(&c.queue, it.index)
+	delete(c.items, it.key)
+}
@@ -0,0 +1,136 @@
+package expiry
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func keys(c *Cache) []string {
+	var keys []string
+	c.Range(func(key string, _ interface{}) {
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+func TestCache(t *testing.T) {
+	now := time.Unix(1552586400, 0)
+	at := func(d time.Duration) time.Time { return now.Add(d) }
+
+	tests := []struct {
+		name string
+		// set are stored in order at now, with the expiry of their index.
+		set     []string
+		expires []time.Duration
+		// then is stored last, at thenAt.
+		then   string
+		thenAt time.Duration
+		want   []string
+	}{
+		{
+			name:    "room left",
+			set:     []string{"a", "b"},
+			expires: []time.Duration{time.Minute, time.Second},
+			then:    "c",
+			want:    []string{"a", "b", "c"},
+		},
+		{
+			name:    "full drops the first to expire",
+			set:     []string{"a", "b", "c"},
+			expires: []time.Duration{time.Minute, time.Second, time.Hour},
+			then:    "d",
+			want:    []string{"a", "c", "d"},
+		},
+		{
+			name:    "expired are dropped first",
+			set:     []string{"a", "b", "c"},
+			expires: []time.Duration{time.Second, 2 * time.Second, time.Hour},
+			then:    "d",
+			thenAt:  2 * time.Second,
+			want:    []string{"c", "d"},
+		},
+		{
+			name:    "replacing does not evict",
+			set:     []string{"a", "b", "c"},
+			expires: []time.Duration{time.Second, time.Minute, time.Hour},
+			then:    "a",
+			want:    []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(3)
+			for i, key := range tt.set {
+				c.Set(key, i, at(tt.expires[i]), now)
+			}
+			c.Set(tt.then, "then", at(tt.thenAt+time.Minute), at(tt.thenAt))
+
+			got := keys(c)
+			if len(got) != len(tt.want) {
+				t.Fatalf("keys = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("keys = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheGet(t *testing.T) {
+	now := time.Unix(1552586400, 0)
+	c := New(2)
+	c.Set("a", 1, now.Add(time.Minute), now)
+
+	v, expires, ok := c.Get("a", now)
+	if !ok || v != 1 || !expires.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Get() = %v, %v, %v", v, expires, ok)
+	}
+
+	// Replacing a value moves its expiry.
+	c.Set("a", 2, now.Add(time.Second), now)
+	c.Set("b", 3, now.Add(time.Hour), now)
+	c.Set("c", 4, now.Add(time.Hour), now)
+	if _, _, ok := c.Get("a", now); ok {
+		t.Fatal("a kept after expiring first")
+	}
+
+	if _, _, ok := c.Get("b", now.Add(time.Hour)); ok {
+		t.Fatal("b returned once expired")
+	}
+	if c.Len() != 1 {
+		t.Fatalf("Len() = %d, want the expired value dropped", c.Len())
+	}
+
+	c.Delete("c")
+	if _, _, ok := c.Get("c", now); ok || c.Len() != 0 {
+		t.Fatal("c returned once deleted")
+	}
+}
+
+func TestCacheRangeDelete(t *testing.T) {
+	now := time.Unix(1552586400, 0)
+	c := New(10)
+	for i, key := range []string{"a", "b", "c", "d"} {
+		c.Set(key, i%2, now.Add(time.Duration(i+1)*time.Second), now)
+	}
+
+	c.Range(func(key string, v interface{}) {
+		if v == 1 {
+			c.Delete(key)
+		}
+	})
+
+	if got := keys(c); len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Fatalf("keys = %v, want [a c]", got)
+	}
+	// The heap still orders what is left.
+	c.Set("e", 0, now.Add(time.Hour), now.Add(time.Second))
+	if got := keys(c); len(got) != 2 || got[0] != "c" || got[1] != "e" {
+		t.Fatalf("keys = %v, want [c e]", got)
+	}
+}
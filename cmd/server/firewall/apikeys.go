@@ -0,0 +1,122 @@
+package firewall
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const apiKeyHeaderKey = "X-API-Key"
+
+// Scopes granted to service principals.
+const (
+	// ScopeRead allows queries.
+	ScopeRead = "read"
+	// ScopeWrite allows mutations.
+	ScopeWrite = "write"
+)
+
+// ErrInvalidAPIKey is returned by an APIKeyStore for unknown keys.
+var ErrInvalidAPIKey = errors.New("auth: invalid api key")
+
+// Principal is a service authenticated by an API key.
+type Principal struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// UserID is the user the service acts as, if any.
+	UserID string `json:"user_id,omitempty"`
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func setPrincipalToRequestContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the service authenticated by an API key, ok
+// is false for requests made by users.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// APIKeyStore finds the principal owning an API key.
+type APIKeyStore interface {
+	Lookup(ctx context.Context, key string) (*Principal, error)
+}
+
+// APIKey is an entry of the API keys file, Hash is the hex encoded SHA-256
+// of the key so the file does not hold the keys themselves.
+type APIKey struct {
+	Hash string `json:"hash"`
+	Principal
+}
+
+// MemoryAPIKeyStore is an APIKeyStore over a fixed set of keys.
+type MemoryAPIKeyStore struct {
+	keys map[string]*Principal
+}
+
+// NewMemoryAPIKeyStore creates a store for keys.
+func NewMemoryAPIKeyStore(keys []APIKey) (*MemoryAPIKeyStore, error) {
+	s := &MemoryAPIKeyStore{keys: make(map[string]*Principal, len(keys))}
+	for i := range keys {
+		k := keys[i]
+		hash := strings.ToLower(k.Hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("api key %q: invalid hash", k.Name)
+		}
+		if k.Name == "" {
+			return nil, errors.New("api key: missing name")
+		}
+		if _, ok := s.keys[hash]; ok {
+			return nil, fmt.Errorf("api key %q: duplicated hash", k.Name)
+		}
+		s.keys[hash] = &k.Principal
+	}
+	return s, nil
+}
+
+// LoadAPIKeys reads a JSON array of APIKey from path.
+func LoadAPIKeys(path string) (*MemoryAPIKeyStore, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, fmt.Errorf("api keys: %v", err)
+	}
+	return NewMemoryAPIKeyStore(keys)
+}
+
+// Lookup implements APIKeyStore.
+func (s *MemoryAPIKeyStore) Lookup(ctx context.Context, key string) (*Principal, error) {
+	p, ok := s.keys[HashAPIKey(key)]
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	return p, nil
+}
+
+// HashAPIKey returns the hash of key as stored in the API keys file.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
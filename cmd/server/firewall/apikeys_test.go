@@ -0,0 +1,86 @@
+package firewall
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewMemoryAPIKeyStore(t *testing.T) {
+	hash := HashAPIKey("secret")
+
+	tests := []struct {
+		name string
+		keys []APIKey
+		ok   bool
+	}{
+		{"valid", []APIKey{{Hash: hash, Principal: Principal{Name: "reports"}}}, true},
+		{"upper case hash", []APIKey{{Hash: strings.ToUpper(hash), Principal: Principal{Name: "reports"}}}, true},
+		{"short hash", []APIKey{{Hash: hash[:62], Principal: Principal{Name: "reports"}}}, false},
+		{"not hex", []APIKey{{Hash: "z" + hash[1:], Principal: Principal{Name: "reports"}}}, false},
+		{"plain key", []APIKey{{Hash: "secret", Principal: Principal{Name: "reports"}}}, false},
+		{"no name", []APIKey{{Hash: hash}}, false},
+		{"duplicated hash", []APIKey{
+			{Hash: hash, Principal: Principal{Name: "reports"}},
+			{Hash: strings.ToUpper(hash), Principal: Principal{Name: "billing"}},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewMemoryAPIKeyStore(tt.keys); (err == nil) != tt.ok {
+				t.Fatalf("NewMemoryAPIKeyStore() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAPIKeyLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	err := os.WriteFile(path, []byte(`[
+		{"hash": "`+strings.ToUpper(HashAPIKey("reports-key"))+`", "name": "reports", "scopes": ["read"]},
+		{"hash": "`+HashAPIKey("billing-key")+`", "name": "billing", "scopes": ["read", "write"], "user_id": "user-1"}
+	]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key   string
+		name  string
+		write bool
+	}{
+		{key: "reports-key", name: "reports"},
+		{key: "billing-key", name: "billing", write: true},
+		// The hash itself is not a key.
+		{key: HashAPIKey("reports-key")},
+		{key: "reports-key "},
+		{key: "Reports-key"},
+		{key: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			p, err := s.Lookup(context.Background(), tt.key)
+			if tt.name == "" {
+				if err != ErrInvalidAPIKey {
+					t.Fatalf("Lookup() = %v, %v, want ErrInvalidAPIKey", p, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Name != tt.name || !p.HasScope(ScopeRead) || p.HasScope(ScopeWrite) != tt.write {
+				t.Fatalf("Lookup() = %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package firewall
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRFCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		header   map[string]string
+		cookie   string
+		double   bool
+		rejected bool
+	}{
+		{name: "GET without origin", method: http.MethodGet},
+		{name: "same origin", header: map[string]string{"Origin": "http://gateway.example.com"}},
+		{name: "allowed origin", header: map[string]string{"Origin": "https://app.example.com"}},
+		{name: "allowed referer", header: map[string]string{"Referer": "https://app.example.com/talks?id=1"}},
+		{name: "other origin", header: map[string]string{"Origin": "https://evil.example.com"}, rejected: true},
+		{name: "other scheme", header: map[string]string{"Origin": "http://app.example.com"}, rejected: true},
+		{name: "other referer", header: map[string]string{"Referer": "https://evil.example.com/"}, rejected: true},
+		{name: "null origin", header: map[string]string{"Origin": "null"}, rejected: true},
+		{name: "no origin nor referer", rejected: true},
+		{name: "no origin with X-Requested-With", header: map[string]string{"X-Requested-With": "XMLHttpRequest"}},
+		{name: "no origin with X-CSRF-Token", header: map[string]string{"X-CSRF-Token": "token"}},
+		{
+			name:     "form without custom header",
+			header:   map[string]string{"Origin": "https://app.example.com", "Content-Type": "application/x-www-form-urlencoded"},
+			rejected: true,
+		},
+		{
+			name:   "form with custom header",
+			header: map[string]string{"Origin": "https://app.example.com", "Content-Type": "text/plain", "X-Requested-With": "fetch"},
+		},
+		{
+			name:   "double submit",
+			header: map[string]string{"X-CSRF-Token": "token"},
+			cookie: "token",
+			double: true,
+		},
+		{
+			name:     "double submit mismatch",
+			header:   map[string]string{"Origin": "https://app.example.com", "X-CSRF-Token": "token"},
+			cookie:   "other",
+			double:   true,
+			rejected: true,
+		},
+		{
+			name:     "double submit without cookie",
+			header:   map[string]string{"X-CSRF-Token": "token"},
+			double:   true,
+			rejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			r := httptest.NewRequest(method, "http://gateway.example.com/graphql", strings.NewReader("{}"))
+			r.Header.Set("Content-Type", "application/graphql")
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: tt.cookie})
+			}
+
+			c := &CSRF{AllowedOrigins: []string{"https://app.example.com"}, DoubleSubmit: tt.double}
+			if reason := c.check(r); (reason != "") != tt.rejected {
+				t.Fatalf("check() = %q, want rejected %v", reason, tt.rejected)
+			}
+		})
+	}
+}
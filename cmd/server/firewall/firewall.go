@@ -9,6 +9,8 @@ import (
 	"github.com/go-toschool/palermo"
 	"github.com/go-toschool/palermo/auth"
 	"github.com/go-toschool/sicily"
+	"github.com/go-toschool/sicily/cmd/server/logging"
+	"github.com/go-toschool/sicily/cmd/server/tracing"
 )
 
 const (
@@ -23,17 +25,35 @@ const (
 // CorsAndToken ...
 type CorsAndToken struct {
 	SessionService auth.AuthServiceClient
+
+	// Sessions keeps the sessions validated by palermo, nil validates every
+	// request against palermo.
+	Sessions *SessionCache
+	// APIKeys authenticates the services sending an X-API-Key header, nil
+	// rejects them.
+	APIKeys APIKeyStore
+	// JWT verifies locally the Bearer tokens that are JWTs, nil sends every
+	// token to palermo.
+	JWT *JWTVerifier
+	// CSRF protects the requests authenticated by the access_token cookie,
+	// nil disables it.
+	CSRF *CSRF
 }
 
 // CheckCorsAndAuth ...
 func (ac *CorsAndToken) CheckCorsAndToken(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Content-Length, Accept-Encoding")
-		if r.Method == "OPTIONS" {
-			w.Header().Set("Access-Control-Max-Age", "86400")
-			w.WriteHeader(http.StatusOK)
+		if cors(w, r) {
+			return
+		}
+
+		if key := r.Header.Get(apiKeyHeaderKey); key != "" {
+			ac.checkAPIKey(next, key, w, r)
+			return
+		}
+
+		if token, err := parseAuthToken(r); err == nil && ac.JWT != nil && isJWT(token) {
+			ac.checkJWT(next, token, w, r)
 			return
 		}
 
@@ -43,24 +63,183 @@ func (ac *CorsAndToken) CheckCorsAndToken(next http.Handler) http.HandlerFunc {
 			return
 		}
 
-		ctx := context.Background()
-		session, err := ac.SessionService.Get(ctx, &auth.GetRequest{
-			Data: &auth.SessionCredentials{
-				ValidationToken: cred.ValidationToken,
-				AuthToken:       cred.AuthToken,
-			},
-		})
+		if !ac.checkCSRF(w, r) {
+			return
+		}
+
+		session, err := ac.session(r.Context(), cred)
 		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
 		ctx1 := r.Context()
-		ctx1 = setUserIDToRequestContext(ctx1, session.Data.UserId)
+		logging.SetUser(ctx1, session.UserId)
+		ctx1 = setUserIDToRequestContext(ctx1, session.UserId)
+		ctx1 = withIdentity(ctx1, &Identity{
+			UserID:    session.UserId,
+			SessionID: session.Id,
+			AuthToken: cred.AuthToken,
+			Session:   session,
+		})
 		next.ServeHTTP(w, r.WithContext(ctx1))
 	}
 }
 
+// checkAPIKey authenticates a service by its API key.
+func (ac *CorsAndToken) checkAPIKey(next http.Handler, key string, w http.ResponseWriter, r *http.Request) {
+	if ac.APIKeys == nil {
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return
+	}
+
+	p, err := ac.APIKeys.Lookup(r.Context(), key)
+	if err != nil {
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	logging.SetUser(ctx, p.UserID)
+	ctx = setUserIDToRequestContext(ctx, p.UserID)
+	ctx = setPrincipalToRequestContext(ctx, p)
+	ctx = withIdentity(ctx, &Identity{
+		UserID:    p.UserID,
+		Principal: p.Name,
+	})
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// checkJWT authenticates a user by a JWT, without asking palermo.
+func (ac *CorsAndToken) checkJWT(next http.Handler, token string, w http.ResponseWriter, r *http.Request) {
+	claims, err := ac.JWT.Verify(token)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	logging.SetUser(ctx, claims.Subject)
+	ctx = setUserIDToRequestContext(ctx, claims.Subject)
+	ctx = setClaimsToRequestContext(ctx, claims)
+	ctx = withIdentity(ctx, &Identity{
+		UserID:    claims.Subject,
+		AuthToken: token,
+	})
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Logout closes the session of the caller in palermo and drops it from the
+// session cache.
+func (ac *CorsAndToken) Logout(w http.ResponseWriter, r *http.Request) {
+	if cors(w, r) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "This server does not support that HTTP method", http.StatusBadRequest)
+		return
+	}
+
+	cred, err := parseAuthCredentials(r)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	if !ac.checkCSRF(w, r) {
+		return
+	}
+
+	ctx, span := tracing.Start(r.Context(), "firewall.Logout")
+	_, err = ac.SessionService.Delete(ctx, &auth.DeleteRequest{
+		Data: &auth.SessionCredentials{
+			ValidationToken: cred.ValidationToken,
+			AuthToken:       cred.AuthToken,
+		},
+	})
+	tracing.End(span, err)
+	if ac.Sessions != nil {
+		ac.Sessions.Invalidate(cred)
+	}
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   authTokenCookieName,
+		Path:   "/",
+		MaxAge: -1,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkCSRF answers with 403 the requests that may be forged, it reports
+// whether r can go on.
+func (ac *CorsAndToken) checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if ac.CSRF == nil {
+		return true
+	}
+
+	if reason := ac.CSRF.check(r); reason != "" {
+		http.Error(w, reason, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// session validates cred against palermo, or the session cache when enabled.
+func (ac *CorsAndToken) session(ctx context.Context, cred *palermo.SessionCredentials) (*auth.Session, error) {
+	if ac.Sessions != nil {
+		if session, ok := ac.Sessions.get(cred); ok {
+			if session == nil {
+				return nil, errors.New("auth: invalid credentials")
+			}
+			return session, nil
+		}
+	}
+
+	ctx, span := tracing.Start(ctx, "firewall.CheckCorsAndToken")
+	res, err := ac.SessionService.Get(ctx, &auth.GetRequest{
+		Data: &auth.SessionCredentials{
+			ValidationToken: cred.ValidationToken,
+			AuthToken:       cred.AuthToken,
+		},
+	})
+	tracing.End(span, err)
+
+	var session *auth.Session
+	if err == nil {
+		session = res.Data
+	}
+	if ac.Sessions != nil {
+		ac.Sessions.add(cred, session, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, errors.New("auth: invalid credentials")
+	}
+	return session, nil
+}
+
+// cors writes the CORS headers, it reports whether the request was a
+// preflight and has been answered.
+func cors(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+	w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Content-Length, Accept-Encoding, Idempotency-Key, X-Request-ID, X-API-Key, X-CSRF-Token, X-Requested-With")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-CSRF-Token")
+	if r.Method != "OPTIONS" {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Max-Age", "86400")
+	w.WriteHeader(http.StatusOK)
+	return true
+}
+
 func NewAuth(ss auth.AuthServiceClient) *CorsAndToken {
 	return &CorsAndToken{
 		SessionService: ss,
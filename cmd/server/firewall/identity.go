@@ -0,0 +1,89 @@
+package firewall
+
+import (
+	"context"
+
+	"github.com/go-toschool/palermo/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	UserID    string
+	SessionID string
+	AuthToken string
+	// Principal is the name of the service authenticated by an API key,
+	// empty for users.
+	Principal string
+	// Session is the palermo session of a user authenticated by one.
+	Session *auth.Session
+}
+
+type identityKey struct{}
+
+func withIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the caller authenticated by CheckCorsAndToken.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// ForwardOptions configures the identity metadata sent to the backend
+// services, so they can enforce their own authorization.
+type ForwardOptions struct {
+	// UserIDKey and SessionIDKey are the metadata keys of the user and
+	// session ids, an empty key does not send the value.
+	UserIDKey    string
+	SessionIDKey string
+	// TokenKey is the metadata key of the auth token of the caller.
+	TokenKey string
+	// TokenServices are the services receiving the auth token.
+	TokenServices []string
+}
+
+// DefaultForwardOptions sends the user and session ids to every service
+// and the auth token to none.
+var DefaultForwardOptions = ForwardOptions{
+	UserIDKey:    "user_id",
+	SessionIDKey: "session_id",
+	TokenKey:     tokenMetaKey,
+}
+
+// ForwardIdentity returns a grpc.UnaryClientInterceptor adding the identity
+// of the caller to the calls made to service on behalf of a request.
+func ForwardIdentity(service string, opts ForwardOptions) grpc.UnaryClientInterceptor {
+	token := false
+	for _, s := range opts.TokenServices {
+		if s == service {
+			token = true
+		}
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		id, ok := IdentityFromContext(ctx)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		kv := make([]string, 0, 6)
+		if opts.UserIDKey != "" && id.UserID != "" {
+			kv = append(kv, opts.UserIDKey, id.UserID)
+		}
+		if opts.SessionIDKey != "" && id.SessionID != "" {
+			kv = append(kv, opts.SessionIDKey, id.SessionID)
+		}
+		if token && opts.TokenKey != "" && id.AuthToken != "" {
+			kv = append(kv, opts.TokenKey, id.AuthToken)
+		}
+		if len(kv) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, kv...)
+		}
+
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
@@ -0,0 +1,232 @@
+package firewall
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Signing algorithms accepted for JWTs.
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// minRSABits is the smallest RSA modulus accepted, shorter keys can be
+// factored.
+const minRSABits = 2048
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// KeySet keeps the JWKS loaded from a file, or every .json file of a
+// directory, and swaps it on Reload, watch.Files reloads it whenever the
+// files change or the process receives SIGHUP, so keys can be rotated by
+// adding the new kid before signing with it.
+type KeySet struct {
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]*publicKey
+}
+
+// NewKeySet loads the keys under path and returns a KeySet serving them.
+func NewKeySet(path string) (*KeySet, error) {
+	ks := &KeySet{path: path}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// Reload reads the keys from disk, keeping the previous ones if the new files
+// cannot be loaded.
+func (ks *KeySet) Reload() error {
+	files, _, err := ks.files()
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*publicKey)
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("jwks: %v", err)
+		}
+
+		var set jwks
+		if err := json.Unmarshal(b, &set); err != nil {
+			return fmt.Errorf("jwks: %s: %v", name, err)
+		}
+
+		for _, k := range set.Keys {
+			if k.Use != "" && k.Use != "sig" {
+				continue
+			}
+			if _, ok := keys[k.Kid]; ok {
+				return fmt.Errorf("jwks: %s: duplicated kid %q", name, k.Kid)
+			}
+
+			pk, err := parseJWK(k)
+			if err != nil {
+				return fmt.Errorf("jwks: %s: kid %q: %v", name, k.Kid, err)
+			}
+			keys[k.Kid] = pk
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("jwks: no signing keys in %s", ks.path)
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+
+	return nil
+}
+
+// key returns the key identified by kid, a token without kid may only be
+// verified by a set holding a single key.
+func (ks *KeySet) key(kid string) (*publicKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if kid == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			return k, true
+		}
+	}
+
+	k, ok := ks.keys[kid]
+	return k, ok
+}
+
+// ModTime returns the last modification of the JWKS files.
+func (ks *KeySet) ModTime() (time.Time, error) {
+	_, modTime, err := ks.files()
+	return modTime, err
+}
+
+// files lists the JWKS files under path and their last modification, the
+// directory itself counts so removing a file is noticed.
+func (ks *KeySet) files() ([]string, time.Time, error) {
+	info, err := os.Stat(ks.path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("jwks: %v", err)
+	}
+	if !info.IsDir() {
+		return []string{ks.path}, info.ModTime(), nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(ks.path, "*.json"))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("jwks: %v", err)
+	}
+	sort.Strings(files)
+
+	last := info.ModTime()
+	for _, name := range files {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("jwks: %v", err)
+		}
+		if fi.ModTime().After(last) {
+			last = fi.ModTime()
+		}
+	}
+
+	return files, last, nil
+}
+
+func parseJWK(k jwk) (*publicKey, error) {
+	var pk *publicKey
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		if n.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key shorter than %d bits", minRSABits)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		pk = &publicKey{AlgRS256, &rsa.PublicKey{N: n, E: int(e.Int64())}}
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point not on curve")
+		}
+		pk = &publicKey{AlgES256, &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		pk = &publicKey{AlgEdDSA, ed25519.PublicKey(x)}
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	if k.Alg != "" && k.Alg != pk.alg {
+		return nil, fmt.Errorf("unsupported algorithm %q", k.Alg)
+	}
+	return pk, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,225 @@
+package firewall
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidJWT is returned by JWTVerifier for tokens that can not be
+// trusted.
+var ErrInvalidJWT = errors.New("auth: invalid jwt")
+
+// JWTOptions describes the tokens accepted by a JWTVerifier.
+type JWTOptions struct {
+	// Issuer and Audience must match the iss and aud claims, empty accepts
+	// any.
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+	// RolesClaim is the claim holding the roles of the subject.
+	RolesClaim string
+}
+
+// DefaultJWTOptions reads the roles from the roles claim.
+var DefaultJWTOptions = JWTOptions{
+	Leeway:     30 * time.Second,
+	RolesClaim: "roles",
+}
+
+// Claims are the claims of a verified JWT.
+type Claims struct {
+	Subject   string
+	Roles     []string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// HasScope reports whether the token grants scope.
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type claimsKey struct{}
+
+func setClaimsToRequestContext(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// ClaimsFromContext returns the claims of the JWT authenticating the
+// request, ok is false for requests authenticated by palermo or an API key.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(*Claims)
+	return c, ok
+}
+
+// JWTVerifier verifies signed JWTs against the keys of a KeySet, without
+// asking palermo.
+type JWTVerifier struct {
+	keys *KeySet
+	opts JWTOptions
+	now  func() time.Time
+}
+
+// NewJWTVerifier creates a verifier of the tokens signed by keys.
+func NewJWTVerifier(keys *KeySet, opts JWTOptions) *JWTVerifier {
+	return &JWTVerifier{
+		keys: keys,
+		opts: opts,
+		now:  time.Now,
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature and the registered claims of token and
+// returns its claims.
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidJWT
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidJWT
+	}
+
+	key, ok := v.keys.key(header.Kid)
+	if !ok || key.alg != header.Alg {
+		return nil, ErrInvalidJWT
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+	if !verifySignature(key, parts[0]+"."+parts[1], sig) {
+		return nil, ErrInvalidJWT
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidJWT
+	}
+
+	return v.checkClaims(claims)
+}
+
+func (v *JWTVerifier) checkClaims(claims map[string]interface{}) (*Claims, error) {
+	now := v.now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || !now.Before(time.Unix(int64(exp), 0).Add(v.opts.Leeway)) {
+		return nil, ErrInvalidJWT
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.opts.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, ErrInvalidJWT
+	}
+	if v.opts.Issuer != "" && claims["iss"] != v.opts.Issuer {
+		return nil, ErrInvalidJWT
+	}
+	if v.opts.Audience != "" && !contains(stringsClaim(claims["aud"]), v.opts.Audience) {
+		return nil, ErrInvalidJWT
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, ErrInvalidJWT
+	}
+
+	c := &Claims{
+		Subject:   sub,
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}
+	if v.opts.RolesClaim != "" {
+		c.Roles = stringsClaim(claims[v.opts.RolesClaim])
+	}
+	// scope is a space separated string (RFC 8693), scp a list.
+	if scope, ok := claims["scope"].(string); ok {
+		c.Scopes = strings.Fields(scope)
+	} else {
+		c.Scopes = stringsClaim(claims["scp"])
+	}
+
+	return c, nil
+}
+
+func verifySignature(key *publicKey, signed string, sig []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch k := key.key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		if len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, []byte(signed), sig)
+	default:
+		return false
+	}
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// stringsClaim reads a claim holding a string or a list of strings.
+func stringsClaim(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		ss := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				ss = append(ss, s)
+			}
+		}
+		return ss
+	default:
+		return nil
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isJWT reports whether token looks like a compact JWT rather than a palermo
+// auth token.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
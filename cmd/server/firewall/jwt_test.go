@@ -0,0 +1,328 @@
+package firewall
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var b64 = base64.RawURLEncoding
+
+// testKeys are the private keys of the JWKS written by writeJWKS.
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+	ed  ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, dk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rk, ec: ek, ed: dk}
+}
+
+func rsaJWK(kid string, k *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"n":   b64.EncodeToString(k.N.Bytes()),
+		"e":   b64.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) map[string]string {
+	x, y := make([]byte, 32), make([]byte, 32)
+	k.X.FillBytes(x)
+	k.Y.FillBytes(y)
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64.EncodeToString(x), "y": b64.EncodeToString(y)}
+}
+
+func edJWK(kid string, k ed25519.PublicKey) map[string]string {
+	return map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": b64.EncodeToString(k)}
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	t.Helper()
+
+	b, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func segment(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b64.EncodeToString(b)
+}
+
+// sign returns a JWT with the given header and claims signed by key, nil
+// leaves the signature empty.
+func sign(t *testing.T, header map[string]string, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+
+	signed := segment(t, header) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signed))
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	}
+	return signed + "." + b64.EncodeToString(sig)
+}
+
+func TestJWTVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path,
+		rsaJWK("rsa", &keys.rsa.PublicKey),
+		ecJWK("ec", &keys.ec.PublicKey),
+		edJWK("ed", keys.ed.Public().(ed25519.PublicKey)),
+	)
+	ks, err := NewKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1552586400, 0)
+	v := NewJWTVerifier(ks, JWTOptions{
+		Issuer:     "https://issuer.example.com",
+		Audience:   "sicily",
+		Leeway:     30 * time.Second,
+		RolesClaim: "roles",
+	})
+	v.now = func() time.Time { return now }
+
+	// claims returns valid claims changed by kv, a nil value removes a claim.
+	claims := func(kv ...interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub": "user-1",
+			"iss": "https://issuer.example.com",
+			"aud": "sicily",
+			"exp": now.Add(time.Minute).Unix(),
+		}
+		for i := 0; i < len(kv); i += 2 {
+			if kv[i+1] == nil {
+				delete(c, kv[i].(string))
+				continue
+			}
+			c[kv[i].(string)] = kv[i+1]
+		}
+		return c
+	}
+	rs256 := map[string]string{"alg": "RS256", "kid": "rsa"}
+	// The HMAC secret of an attacker reusing the public key of the set.
+	rsaPublic := []byte(rsaJWK("rsa", &keys.rsa.PublicKey)["n"])
+
+	tests := []struct {
+		name  string
+		token string
+		want  *Claims
+	}{
+		{
+			name:  "RS256",
+			token: sign(t, rs256, claims("roles", []string{"admin"}, "scope", "read write"), keys.rsa),
+			want:  &Claims{Subject: "user-1", Roles: []string{"admin"}, Scopes: []string{"read", "write"}, ExpiresAt: now.Add(time.Minute)},
+		},
+		{
+			name:  "ES256 with scp list",
+			token: sign(t, map[string]string{"alg": "ES256", "kid": "ec"}, claims("scp", []string{"read"}), keys.ec),
+			want:  &Claims{Subject: "user-1", Scopes: []string{"read"}, ExpiresAt: now.Add(time.Minute)},
+		},
+		{
+			name:  "EdDSA with audience list",
+			token: sign(t, map[string]string{"alg": "EdDSA", "kid": "ed"}, claims("aud", []string{"other", "sicily"}), keys.ed),
+			want:  &Claims{Subject: "user-1", ExpiresAt: now.Add(time.Minute)},
+		},
+		{name: "alg none", token: sign(t, map[string]string{"alg": "none", "kid": "rsa"}, claims(), nil)},
+		{name: "alg none without kid", token: sign(t, map[string]string{"alg": "none"}, claims(), nil)},
+		{name: "HS256 with the RSA key", token: sign(t, map[string]string{"alg": "HS256", "kid": "rsa"}, claims(), rsaPublic)},
+		{name: "ES256 header on the RSA key", token: sign(t, map[string]string{"alg": "ES256", "kid": "rsa"}, claims(), keys.ec)},
+		{name: "RS256 header on the EC key", token: sign(t, map[string]string{"alg": "RS256", "kid": "ec"}, claims(), keys.rsa)},
+		{name: "unknown kid", token: sign(t, map[string]string{"alg": "RS256", "kid": "gone"}, claims(), keys.rsa)},
+		{name: "no kid with several keys", token: sign(t, map[string]string{"alg": "RS256"}, claims(), keys.rsa)},
+		{name: "tampered claims", token: func() string {
+			parts := strings.Split(sign(t, rs256, claims(), keys.rsa), ".")
+			parts[1] = segment(t, claims("sub", "user-2"))
+			return strings.Join(parts, ".")
+		}()},
+		{name: "not a JWT", token: "a.b"},
+		{
+			name:  "expired within leeway",
+			token: sign(t, rs256, claims("exp", now.Add(-10*time.Second).Unix()), keys.rsa),
+			want:  &Claims{Subject: "user-1", ExpiresAt: now.Add(-10 * time.Second)},
+		},
+		{name: "expired past leeway", token: sign(t, rs256, claims("exp", now.Add(-time.Minute).Unix()), keys.rsa)},
+		{name: "no exp", token: sign(t, rs256, claims("exp", nil), keys.rsa)},
+		{
+			name:  "nbf within leeway",
+			token: sign(t, rs256, claims("nbf", now.Add(10*time.Second).Unix()), keys.rsa),
+			want:  &Claims{Subject: "user-1", ExpiresAt: now.Add(time.Minute)},
+		},
+		{name: "nbf past leeway", token: sign(t, rs256, claims("nbf", now.Add(time.Minute).Unix()), keys.rsa)},
+		{name: "wrong iss", token: sign(t, rs256, claims("iss", "https://evil.example.com"), keys.rsa)},
+		{name: "no iss", token: sign(t, rs256, claims("iss", nil), keys.rsa)},
+		{name: "wrong aud", token: sign(t, rs256, claims("aud", "other"), keys.rsa)},
+		{name: "no aud", token: sign(t, rs256, claims("aud", nil), keys.rsa)},
+		{name: "no sub", token: sign(t, rs256, claims("sub", nil), keys.rsa)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token)
+			if tt.want == nil {
+				if err != ErrInvalidJWT {
+					t.Fatalf("Verify() = %+v, %v, want ErrInvalidJWT", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySetReload(t *testing.T) {
+	keys := newTestKeys(t)
+	dir := t.TempDir()
+	writeJWKS(t, filepath.Join(dir, "a.json"), rsaJWK("rsa", &keys.rsa.PublicKey))
+	writeJWKS(t, filepath.Join(dir, "b.json"), edJWK("ed", keys.ed.Public().(ed25519.PublicKey)))
+
+	ks, err := NewKeySet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewJWTVerifier(ks, JWTOptions{})
+	token := sign(t, map[string]string{"alg": "EdDSA", "kid": "ed"}, map[string]interface{}{
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}, keys.ed)
+
+	if _, err := v.Verify(token); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// A file that can not be loaded keeps the current keys.
+	if err := os.WriteFile(filepath.Join(dir, "c.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Reload(); err == nil {
+		t.Fatal("Reload() accepted an invalid file")
+	}
+	if _, err := v.Verify(token); err != nil {
+		t.Fatalf("Verify() after a failed reload error = %v", err)
+	}
+
+	// The key rotated out is no longer accepted.
+	os.Remove(filepath.Join(dir, "c.json"))
+	os.Remove(filepath.Join(dir, "b.json"))
+	if err := ks.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(token); err != ErrInvalidJWT {
+		t.Fatalf("Verify() with a rotated key error = %v, want ErrInvalidJWT", err)
+	}
+}
+
+func TestParseJWK(t *testing.T) {
+	keys := newTestKeys(t)
+	short, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withAlg := func(k map[string]string, alg string) map[string]string {
+		k["alg"] = alg
+		return k
+	}
+	tests := []struct {
+		name string
+		key  map[string]string
+		ok   bool
+	}{
+		{"RSA 2048", rsaJWK("rsa", &keys.rsa.PublicKey), true},
+		{"RSA 1024", rsaJWK("rsa", &short.PublicKey), false},
+		{"RSA declared HS256", withAlg(rsaJWK("rsa", &keys.rsa.PublicKey), "HS256"), false},
+		{"EC P-256", ecJWK("ec", &keys.ec.PublicKey), true},
+		{"EC other curve", map[string]string{"kty": "EC", "crv": "P-384", "x": "AQ", "y": "AQ"}, false},
+		{"EC point off the curve", map[string]string{"kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}, false},
+		{"Ed25519", edJWK("ed", keys.ed.Public().(ed25519.PublicKey)), true},
+		{"symmetric", map[string]string{"kty": "oct", "k": "c2VjcmV0"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := json.Marshal(tt.key)
+			var k jwk
+			if err := json.Unmarshal(b, &k); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parseJWK(k); (err == nil) != tt.ok {
+				t.Fatalf("parseJWK() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+
+	// A set holding a short key is refused as a whole.
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, rsaJWK("rsa", &keys.rsa.PublicKey), rsaJWK("short", &short.PublicKey))
+	if _, err := NewKeySet(path); err == nil {
+		t.Fatal("NewKeySet() accepted a 1024 bits RSA key")
+	}
+}
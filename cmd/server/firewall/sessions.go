@@ -0,0 +1,115 @@
+package firewall
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/go-toschool/palermo"
+	"github.com/go-toschool/palermo/auth"
+	"github.com/go-toschool/sicily/cmd/server/expiry"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var sessionCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sicily_session_cache_requests_total",
+	Help: "Session validations by result: hit, negative_hit or miss.",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(sessionCacheRequests)
+}
+
+// SessionCache keeps the sessions validated by palermo for a short time, so
+// every request does not wait for palermo, and the rejected credentials for
+// an even shorter time.
+type SessionCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu sync.Mutex
+	// entries hold the *auth.Session of the credentials, nil for the
+	// credentials palermo rejected.
+	entries *expiry.Cache
+}
+
+// NewSessionCache creates a cache keeping up to size sessions during ttl,
+// and rejected credentials during negativeTTL.
+func NewSessionCache(size int, ttl, negativeTTL time.Duration) *SessionCache {
+	return &SessionCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     expiry.New(size),
+	}
+}
+
+// get returns the session stored for cred, a nil session with ok set when
+// cred was rejected.
+func (c *SessionCache) get(cred *palermo.SessionCredentials) (*auth.Session, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, _, found := c.entries.Get(sessionKey(cred), c.now())
+	if !found {
+		sessionCacheRequests.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	session := v.(*auth.Session)
+	if session == nil {
+		sessionCacheRequests.WithLabelValues("negative_hit").Inc()
+	} else {
+		sessionCacheRequests.WithLabelValues("hit").Inc()
+	}
+	return session, true
+}
+
+// add stores the answer of palermo for cred. Errors not telling the
+// credentials are invalid, like palermo being unavailable, are not stored.
+func (c *SessionCache) add(cred *palermo.SessionCredentials, session *auth.Session, err error) {
+	ttl := c.ttl
+	if err != nil || session == nil {
+		if !rejected(err) || c.negativeTTL <= 0 {
+			return
+		}
+		session, ttl = nil, c.negativeTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.entries.Set(sessionKey(cred), session, now.Add(ttl), now)
+}
+
+// Invalidate forgets the session of cred.
+func (c *SessionCache) Invalidate(cred *palermo.SessionCredentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries.Delete(sessionKey(cred))
+}
+
+// sessionKey hashes the credentials so the cache does not hold the tokens.
+func sessionKey(cred *palermo.SessionCredentials) string {
+	h := sha256.New()
+	h.Write([]byte(cred.AuthToken))
+	h.Write([]byte{0})
+	h.Write([]byte(cred.ValidationToken))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// rejected reports whether err tells the credentials are invalid.
+func rejected(err error) bool {
+	switch status.Code(err) {
+	case codes.OK, codes.Unauthenticated, codes.NotFound, codes.PermissionDenied, codes.InvalidArgument:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package firewall
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-toschool/palermo"
+	"github.com/go-toschool/palermo/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSessionCache(t *testing.T) {
+	now := time.Unix(1552586400, 0)
+	ada := &palermo.SessionCredentials{AuthToken: "ada", ValidationToken: "ada-validation"}
+	forged := &palermo.SessionCredentials{AuthToken: "forged", ValidationToken: "forged"}
+	down := &palermo.SessionCredentials{AuthToken: "grace", ValidationToken: "grace-validation"}
+	session := &auth.Session{Id: "session-1", UserId: "user-1"}
+
+	c := NewSessionCache(10, time.Minute, 5*time.Second)
+	c.now = func() time.Time { return now }
+
+	c.add(ada, session, nil)
+	c.add(forged, nil, status.Error(codes.Unauthenticated, "invalid token"))
+	c.add(down, nil, status.Error(codes.Unavailable, "palermo is down"))
+
+	tests := []struct {
+		name    string
+		cred    *palermo.SessionCredentials
+		after   time.Duration
+		session *auth.Session
+		ok      bool
+	}{
+		{"hit", ada, 0, session, true},
+		{"hit before ttl", ada, 59 * time.Second, session, true},
+		{"miss after ttl", ada, time.Minute, nil, false},
+		{"negative hit", forged, 0, nil, true},
+		{"miss after negative ttl", forged, 5 * time.Second, nil, false},
+		{"unavailable not stored", down, 0, nil, false},
+		{"other credentials", &palermo.SessionCredentials{AuthToken: "ada", ValidationToken: "other"}, 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.now = func() time.Time { return now.Add(tt.after) }
+			got, ok := c.get(tt.cred)
+			if got != tt.session || ok != tt.ok {
+				t.Fatalf("get() = %v, %v, want %v, %v", got, ok, tt.session, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSessionCacheInvalidate(t *testing.T) {
+	ada := &palermo.SessionCredentials{AuthToken: "ada", ValidationToken: "ada-validation"}
+
+	c := NewSessionCache(10, time.Minute, time.Minute)
+	c.add(ada, &auth.Session{Id: "session-1", UserId: "user-1"}, nil)
+	c.Invalidate(ada)
+
+	if session, ok := c.get(ada); ok {
+		t.Fatalf("get() = %v after Invalidate", session)
+	}
+}
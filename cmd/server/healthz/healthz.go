@@ -1,25 +1,121 @@
 package healthz
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
-type healthzHandler struct{}
+// Component is a dependency of the gateway reported by the health endpoint.
+type Component interface {
+	Name() string
+	// Status returns a description of the state of the component and
+	// whether it is usable.
+	Status() (string, bool)
+}
+
+// Endpoints is implemented by the components reaching several addresses,
+// the health endpoint lists the state of each of them.
+type Endpoints interface {
+	Endpoints() map[string]string
+}
+
+// Ready is implemented by the components the gateway can not serve
+// requests without, the readiness endpoint fails while one is not ready.
+type Ready interface {
+	Ready() bool
+}
+
+type healthzResponse struct {
+	Status     string                       `json:"status"`
+	Components map[string]string            `json:"components,omitempty"`
+	Endpoints  map[string]map[string]string `json:"endpoints,omitempty"`
+}
+
+type healthzHandler struct {
+	components []Component
+}
 
+// ServeHTTP answers 200 while the gateway runs, reporting it as degraded
+// when a component is unusable so probes do not restart it because of a
+// downstream outage.
 func (h *healthzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res := &healthzResponse{Status: "ok"}
+	if len(h.components) > 0 {
+		res.Components = make(map[string]string, len(h.components))
+	}
+
+	for _, c := range h.components {
+		state, healthy := c.Status()
+		res.Components[c.Name()] = state
+		if !healthy {
+			res.Status = "degraded"
+		}
+
+		if e, ok := c.(Endpoints); ok {
+			if res.Endpoints == nil {
+				res.Endpoints = make(map[string]map[string]string)
+			}
+			res.Endpoints[c.Name()] = e.Endpoints()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func newHealthz(components []Component) *healthzHandler {
+	return &healthzHandler{components}
+}
+
+type readyzResponse struct {
+	Status     string            `json:"status"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+type readyzHandler struct {
+	components []Component
+}
+
+// ServeHTTP answers 503 while a component is not ready, so load balancers
+// stop sending requests to the gateway until it can reach its services.
+func (h *readyzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res := &readyzResponse{Status: "ready"}
+	code := http.StatusOK
+
+	for _, c := range h.components {
+		ready, ok := c.(Ready)
+		if !ok {
+			continue
+		}
+		if res.Components == nil {
+			res.Components = make(map[string]string)
+		}
+
+		res.Components[c.Name()] = "ready"
+		if !ready.Ready() {
+			res.Components[c.Name()] = "not ready"
+			res.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(res)
 }
 
-func newHealthz() *healthzHandler {
-	return &healthzHandler{}
+func newReadyz(components []Component) *readyzHandler {
+	return &readyzHandler{components}
 }
 
-func Routes() *mux.Router {
+func Routes(components ...Component) *mux.Router {
 	r := mux.NewRouter()
 
-	r.HandleFunc("/healthz", newHealthz().ServeHTTP)
+	r.HandleFunc("/healthz", newHealthz(components).ServeHTTP)
+	r.HandleFunc("/readyz", newReadyz(components).ServeHTTP)
 
 	return r
 }
@@ -0,0 +1,85 @@
+package home
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+const (
+	graphiqlPath     = "/graphiql"
+	graphiqlStatic   = graphiqlPath + "/static/"
+	graphqlEndpoint  = "/graphql"
+	authCookieName   = "access_token"
+	authCookieHeader = "X-Access-Token"
+)
+
+// assets holds the GraphiQL page and, once vendored with `make graphiql`,
+// the GraphiQL and React builds under assets/static. They are never loaded
+// from a CDN, a compromised copy would run with the session of the user.
+//
+//go:embed assets
+var assets embed.FS
+
+// graphiqlAssets lists the vendored files needed by the page.
+var graphiqlAssets = []string{
+	"react.production.min.js",
+	"react-dom.production.min.js",
+	"graphiql.min.js",
+	"graphiql.min.css",
+}
+
+var graphiqlTemplate = template.Must(template.ParseFS(assets, "assets/graphiql.html"))
+
+type graphiqlPage struct {
+	Endpoint     string
+	CookieName   string
+	CookieHeader string
+	Scripts      []string
+	Styles       []string
+}
+
+type graphiqlHandler struct {
+	page   *graphiqlPage
+	static http.Handler
+}
+
+func newGraphiQL() (*graphiqlHandler, error) {
+	static, err := fs.Sub(assets, "assets/static")
+	if err != nil {
+		return nil, err
+	}
+
+	page := &graphiqlPage{
+		Endpoint:     graphqlEndpoint,
+		CookieName:   authCookieName,
+		CookieHeader: authCookieHeader,
+	}
+	for _, name := range graphiqlAssets {
+		if _, err := fs.Stat(static, name); err != nil {
+			return nil, fmt.Errorf("%s is not vendored, run make graphiql before building", name)
+		}
+
+		url := graphiqlStatic + name
+		if strings.HasSuffix(name, ".css") {
+			page.Styles = append(page.Styles, url)
+		} else {
+			page.Scripts = append(page.Scripts, url)
+		}
+	}
+
+	return &graphiqlHandler{
+		page:   page,
+		static: http.StripPrefix(graphiqlStatic, http.FileServer(http.FS(static))),
+	}, nil
+}
+
+func (h *graphiqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := graphiqlTemplate.Execute(w, h.page); err != nil {
+		http.Error(w, "could not render GraphiQL", http.StatusInternalServerError)
+	}
+}
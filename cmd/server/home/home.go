@@ -6,12 +6,24 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func Routes() *mux.Router {
+// Routes serves the root of the gateway, with the GraphiQL IDE under
+// /graphiql when enabled. It fails when GraphiQL is enabled but its assets
+// were not vendored.
+func Routes(graphiql bool) (*mux.Router, error) {
 	r := mux.NewRouter()
 
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
 	})
 
-	return r
+	if graphiql {
+		h, err := newGraphiQL()
+		if err != nil {
+			return nil, err
+		}
+		r.Handle(graphiqlPath, h)
+		r.PathPrefix(graphiqlStatic).Handler(h.static)
+	}
+
+	return r, nil
 }
@@ -0,0 +1,97 @@
+// Package logging sets up the structured logger of the gateway and writes
+// one access log line per HTTP request, correlated with the logs of the
+// backend services through the request id.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// New returns a logger writing to w in format, "json" or "logfmt", the
+// records below level ("debug", "info", "warn" or "error").
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("logging: unknown level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: l}
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "logfmt", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("logging: unknown format %q", format)
+	}
+}
+
+// Entry collects what the handlers of a request learn about it for its
+// access log line.
+type Entry struct {
+	mu         sync.Mutex
+	requestID  string
+	userID     string
+	operation  string
+	errorCodes []string
+}
+
+type entryKey struct{}
+
+func withEntry(ctx context.Context, e *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, e)
+}
+
+func entryFromContext(ctx context.Context) *Entry {
+	e, _ := ctx.Value(entryKey{}).(*Entry)
+	return e
+}
+
+// RequestID returns the id of the request ctx belongs to, empty outside of
+// the Middleware.
+func RequestID(ctx context.Context) string {
+	if e := entryFromContext(ctx); e != nil {
+		return e.requestID
+	}
+	return ""
+}
+
+// SetUser records the authenticated user of the request.
+func SetUser(ctx context.Context, userID string) {
+	if e := entryFromContext(ctx); e != nil {
+		e.mu.Lock()
+		e.userID = userID
+		e.mu.Unlock()
+	}
+}
+
+// SetOperation records the GraphQL operation executed by the request.
+func SetOperation(ctx context.Context, operation string) {
+	if e := entryFromContext(ctx); e != nil {
+		e.mu.Lock()
+		e.operation = operation
+		e.mu.Unlock()
+	}
+}
+
+// AddErrorCodes records the codes of the GraphQL errors of the response.
+func AddErrorCodes(ctx context.Context, codes ...string) {
+	if e := entryFromContext(ctx); e != nil {
+		e.mu.Lock()
+		e.errorCodes = append(e.errorCodes, codes...)
+		e.mu.Unlock()
+	}
+}
+
+// FromContext returns the default logger with the request id of ctx.
+func FromContext(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
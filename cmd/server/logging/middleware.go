@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/urfave/negroni"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// RequestIDHeader carries the id of a request, generated by the gateway
+	// unless the caller sends one.
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDMetaKey carries the request id in the gRPC metadata sent to
+	// the backend services.
+	RequestIDMetaKey = "x-request-id"
+
+	maxRequestIDLength = 128
+)
+
+// Middleware assigns an id to every request, returns it in the
+// X-Request-ID header and logs the request once it is served.
+func Middleware(logger *slog.Logger) negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		start := time.Now()
+
+		e := &Entry{requestID: requestID(r)}
+		w.Header().Set(RequestIDHeader, e.requestID)
+
+		next(w, r.WithContext(withEntry(r.Context(), e)))
+
+		status := http.StatusOK
+		if rw, ok := w.(negroni.ResponseWriter); ok && rw.Status() != 0 {
+			status = rw.Status()
+		}
+
+		e.mu.Lock()
+		attrs := []slog.Attr{
+			slog.String("request_id", e.requestID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		}
+		if e.userID != "" {
+			attrs = append(attrs, slog.String("user_id", e.userID))
+		}
+		if e.operation != "" {
+			attrs = append(attrs, slog.String("operation", e.operation))
+		}
+		if len(e.errorCodes) > 0 {
+			attrs = append(attrs, slog.Any("error_codes", e.errorCodes))
+		}
+		e.mu.Unlock()
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// requestID returns the id sent by the caller when it is usable, a new one
+// otherwise.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// UnaryClientInterceptor forwards the request id of the call context to the
+// backend services.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if id := RequestID(ctx); id != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMetaKey, id)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
@@ -1,30 +1,49 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-toschool/helenia/assistants"
 	"github.com/go-toschool/palermo/auth"
 	"github.com/go-toschool/platon/talks"
-	"github.com/urfave/negroni"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 
 	"github.com/go-toschool/sicily/cmd/server/api"
+	"github.com/go-toschool/sicily/cmd/server/audit"
+	"github.com/go-toschool/sicily/cmd/server/backend"
+	"github.com/go-toschool/sicily/cmd/server/certs"
+	"github.com/go-toschool/sicily/cmd/server/firewall"
 	"github.com/go-toschool/sicily/cmd/server/healthz"
 	"github.com/go-toschool/sicily/cmd/server/home"
+	"github.com/go-toschool/sicily/cmd/server/logging"
 	"github.com/go-toschool/sicily/cmd/server/prometheus"
+	"github.com/go-toschool/sicily/cmd/server/tracing"
+	"github.com/go-toschool/sicily/cmd/server/watch"
 	"github.com/go-toschool/sicily/graph"
-	"github.com/go-toschool/sicily/graph/mutation"
-	"github.com/go-toschool/sicily/graph/queries"
+	"github.com/go-toschool/sicily/graph/schema"
+	"github.com/go-toschool/sicily/mock"
 	"github.com/go-toschool/syracuse/citizens"
-	"github.com/graphql-go/graphql"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		check("schema:", runSchema(os.Args[2:]))
+		return
+	}
+
 	citizensHost := flag.String("citizens-host", "localhost", "Citizens service host")
 	citizensPort := flag.Int64("citizens-port", 8001, "Citizens service port")
 	palermoHost := flag.String("palermo-host", "localhost", "Palermo service host")
@@ -33,74 +52,302 @@ func main() {
 	platoPort := flag.Int64("plato-port", 8004, "Plato service port")
 	heleniaHost := flag.String("helenia-host", "localhost", "Helenia service host")
 	heleniaPort := flag.Int64("helenia-port", 8005, "Helenia service port")
+	citizensAddr := flag.String("citizens-addr", "", "Comma separated addresses or dns:/// target of the citizens service, overrides -citizens-host and -citizens-port")
+	palermoAddr := flag.String("palermo-addr", "", "Comma separated addresses or dns:/// target of the palermo service, overrides -palermo-host and -palermo-port")
+	platoAddr := flag.String("plato-addr", "", "Comma separated addresses or dns:/// target of the plato service, overrides -plato-host and -plato-port")
+	heleniaAddr := flag.String("helenia-addr", "", "Comma separated addresses or dns:/// target of the helenia service, overrides -helenia-host and -helenia-port")
+	citizensTimeout := flag.Duration("citizens-timeout", backend.DefaultOptions.Timeout, "Timeout of each call to Citizens")
+	palermoTimeout := flag.Duration("palermo-timeout", backend.DefaultOptions.Timeout, "Timeout of each call to Palermo")
+	platoTimeout := flag.Duration("plato-timeout", backend.DefaultOptions.Timeout, "Timeout of each call to Plato")
+	heleniaTimeout := flag.Duration("helenia-timeout", backend.DefaultOptions.Timeout, "Timeout of each call to Helenia")
+	backendRetries := flag.Int("backend-retries", backend.DefaultOptions.Retries, "Extra attempts of failed Get and Select calls")
+	backendBackoff := flag.Duration("backend-backoff", backend.DefaultOptions.Backoff, "Base delay between attempts, doubled and jittered on each retry")
+	breakerFailures := flag.Int("breaker-failures", backend.DefaultOptions.FailureThreshold, "Consecutive failures that open the circuit breaker of a service, 0 disables it")
+	breakerCooldown := flag.Duration("breaker-cooldown", backend.DefaultOptions.Cooldown, "How long a circuit breaker stays open before a trial call")
+	backendBalancing := flag.String("backend-balancing", backend.DefaultOptions.Balancing, "How calls are spread over the addresses of a service: round_robin or least_request")
+	backendKeepaliveTime := flag.Duration("backend-keepalive-time", backend.DefaultOptions.Keepalive.Time, "How often idle backend connections are pinged, 0 disables it")
+	backendKeepaliveTimeout := flag.Duration("backend-keepalive-timeout", backend.DefaultOptions.Keepalive.Timeout, "How long a keepalive ping waits for its answer before the connection is closed")
+	port := flag.Int64("port", 3000, "Gateway listening port")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "How long the requests in flight are given to finish on SIGINT or SIGTERM")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file, enables HTTPS when set")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	tlsMinVersion := flag.String("tls-min-version", "1.2", "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+	tlsClientCA := flag.String("tls-client-ca", "", "CA bundle used to verify client certificates")
+	tlsRequireClientCert := flag.Bool("tls-require-client-cert", false, "Reject clients without a valid certificate")
+	tlsReloadInterval := flag.Duration("tls-reload-interval", 30*time.Second, "How often certificate files are checked for changes")
+
+	graphiql := flag.Bool("graphiql", false, "Serve the GraphiQL IDE under /graphiql")
+	idempotencyTTL := flag.Duration("idempotency-ttl", 24*time.Hour, "How long mutation results are kept for Idempotency-Key retries, 0 disables them")
+	responseCacheSize := flag.Int("response-cache-size", 1000, "Number of query results kept in the response cache, 0 disables it")
+	sessionCacheSize := flag.Int("session-cache-size", 10000, "Number of sessions kept in the session cache, 0 disables it")
+	sessionCacheTTL := flag.Duration("session-cache-ttl", 30*time.Second, "How long a session validated by palermo is trusted without asking again")
+	sessionCacheNegativeTTL := flag.Duration("session-cache-negative-ttl", 5*time.Second, "How long credentials rejected by palermo are rejected without asking again, 0 disables it")
+	apiKeys := flag.String("api-keys", "", "JSON file with the hashed API keys accepted in the X-API-Key header, empty disables them")
+	jwtJWKS := flag.String("jwt-jwks", "", "JWKS file, or directory of .json JWKS files, verifying Bearer JWTs locally, empty sends every token to palermo")
+	jwtIssuer := flag.String("jwt-issuer", "", "Required iss claim of the JWTs, must be set with -jwt-jwks")
+	jwtAudience := flag.String("jwt-audience", "", "Required aud claim of the JWTs, must be set with -jwt-jwks")
+	jwtLeeway := flag.Duration("jwt-leeway", firewall.DefaultJWTOptions.Leeway, "Clock skew tolerated when checking exp and nbf")
+	jwtRolesClaim := flag.String("jwt-roles-claim", firewall.DefaultJWTOptions.RolesClaim, "Claim holding the roles of the subject")
+	jwtReloadInterval := flag.Duration("jwt-reload-interval", 30*time.Second, "How often the JWKS files are checked for changes")
+	csrf := flag.Bool("csrf", true, "Reject cookie authenticated requests that may be forged by other sites")
+	csrfAllowedOrigins := flag.String("csrf-allowed-origins", "", "Comma separated origins, e.g. https://app.example.com, allowed to send cookie authenticated requests besides the gateway")
+	csrfDoubleSubmit := flag.Bool("csrf-double-submit", false, "Require the X-CSRF-Token header to match the csrf_token cookie")
+	auditSink := flag.String("audit-sink", "stdout", "Where the audit trail of the mutations is written: none, stdout or file")
+	auditFile := flag.String("audit-file", "audit.jsonl", "Audit file of the file sink")
+	auditMaxSize := flag.Int64("audit-max-size", 100<<20, "Size in bytes at which the audit file is rotated")
+	auditMaxFiles := flag.Int("audit-max-files", 10, "Number of rotated audit files kept")
+	auditKeep := flag.Int("audit-keep", 1000, "Number of audit entries the stdout sink keeps in memory for the audit_log query")
+	adminUsers := flag.String("admin-users", "", "Comma separated ids of the users allowed to read the audit log")
+	adminRole := flag.String("admin-role", "admin", "JWT role allowed to read the audit log")
+	traceExporter := flag.String("trace-exporter", tracing.ExporterNone, "Where spans are exported: none, stdout or otlp")
+	traceEndpoint := flag.String("trace-endpoint", "localhost:4317", "Address of the OTLP collector")
+	traceSampleRatio := flag.Float64("trace-sample-ratio", 1, "Fraction of the traces started by the gateway that are recorded")
+	identityUserKey := flag.String("identity-user-key", firewall.DefaultForwardOptions.UserIDKey, "gRPC metadata key carrying the caller user id, empty to not send it")
+	identitySessionKey := flag.String("identity-session-key", firewall.DefaultForwardOptions.SessionIDKey, "gRPC metadata key carrying the caller session id, empty to not send it")
+	identityTokenKey := flag.String("identity-token-key", firewall.DefaultForwardOptions.TokenKey, "gRPC metadata key carrying the caller auth token")
+	identityTokenServices := flag.String("identity-token-services", "", "Comma separated services receiving the caller auth token: citizens, palermo, plato, helenia")
+	logFormat := flag.String("log-format", "json", "Log format: json or logfmt")
+	logLevel := flag.String("log-level", "info", "Minimum level logged: debug, info, warn or error")
+	mockBackends := flag.Bool("mock-backends", false, "Serve from in-memory backends instead of the gRPC services")
+	mockFixtures := flag.String("mock-fixtures", "", "JSON file used to seed the in-memory backends")
 
 	flag.Parse()
-	// Connect services
-	citizenURL := fmt.Sprintf("%s:%d", *citizensHost, *citizensPort)
-	fmt.Printf("Connecting to: %s\n", citizenURL)
-	citizensConn, err := grpc.Dial(citizenURL, grpc.WithInsecure())
-	check("citizens connection:", err)
-
-	palermoURL := fmt.Sprintf("%s:%d", *palermoHost, *palermoPort)
-	fmt.Printf("Connecting to: %s\n", palermoURL)
-	palermoConn, err := grpc.Dial(palermoURL, grpc.WithInsecure())
-	check("palermo connection:", err)
-
-	platoURL := fmt.Sprintf("%s:%d", *platoHost, *platoPort)
-	fmt.Printf("Connecting to: %s\n", platoURL)
-	platoConn, err := grpc.Dial(platoURL, grpc.WithInsecure())
-	check("plato connection:", err)
-
-	heleniaURL := fmt.Sprintf("%s:%d", *heleniaHost, *heleniaPort)
-	fmt.Printf("Connecting to: %s\n", heleniaURL)
-	heleniaConn, err := grpc.Dial(heleniaURL, grpc.WithInsecure())
-	check("helenia connection:", err)
-
-	// Initialize citizen client
-	citizenSvc := citizens.NewCitizenshipClient(citizensConn)
-	palermoSvc := auth.NewAuthServiceClient(palermoConn)
-	talksSvc := talks.NewTalkingClient(platoConn)
-	assistantSvc := assistants.NewAssistantsClient(heleniaConn)
-
-	graphCtx := &graph.Context{
-		UserService:       citizenSvc,
-		SessionService:    palermoSvc,
-		TalkService:       talksSvc,
-		AssistantsService: assistantSvc,
+
+	logger, err := logging.New(os.Stderr, *logFormat, *logLevel)
+	check("logging:", err)
+	slog.SetDefault(logger)
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		check("tls:", errors.New("-tls-cert and -tls-key must be set together"))
 	}
 
-	// graphql schemas
-	schema, err := graphql.NewSchema(graphql.SchemaConfig{
-		Query:    queries.Queries(graphCtx),
-		Mutation: mutation.Mutations(graphCtx),
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    *traceExporter,
+		Endpoint:    *traceEndpoint,
+		SampleRatio: *traceSampleRatio,
 	})
+	check("tracing:", err)
+
+	backendOptions := func(timeout time.Duration) backend.Options {
+		return backend.Options{
+			Timeout:          timeout,
+			Retries:          *backendRetries,
+			Backoff:          *backendBackoff,
+			FailureThreshold: *breakerFailures,
+			Cooldown:         *breakerCooldown,
+			Balancing:        *backendBalancing,
+			Keepalive: keepalive.ClientParameters{
+				Time:    *backendKeepaliveTime,
+				Timeout: *backendKeepaliveTimeout,
+			},
+		}
+	}
+	// target returns addrs, or host:port when no addresses were given.
+	target := func(addrs, host string, port int64) string {
+		if addrs != "" {
+			return addrs
+		}
+		return fmt.Sprintf("%s:%d", host, port)
+	}
+
+	forward := firewall.ForwardOptions{
+		UserIDKey:    *identityUserKey,
+		SessionIDKey: *identitySessionKey,
+		TokenKey:     *identityTokenKey,
+	}
+	for _, s := range strings.Split(*identityTokenServices, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			forward.TokenServices = append(forward.TokenServices, s)
+		}
+	}
+
+	var graphCtx *graph.Context
+	var components []healthz.Component
+	if *mockBackends {
+		backends, err := mock.Load(*mockFixtures)
+		check("mock backends:", err)
+		slog.Info("using in-memory mock backends", "fixtures", *mockFixtures)
+		graphCtx = backends.Context()
+	} else {
+		citizensBackend := backend.New("citizens", backendOptions(*citizensTimeout))
+		palermoBackend := backend.New("palermo", backendOptions(*palermoTimeout))
+		platoBackend := backend.New("plato", backendOptions(*platoTimeout))
+		heleniaBackend := backend.New("helenia", backendOptions(*heleniaTimeout))
+		components = append(components, citizensBackend, palermoBackend, platoBackend, heleniaBackend)
+
+		graphCtx = &graph.Context{
+			UserService:       citizens.NewCitizenshipClient(dial(citizensBackend, forward, target(*citizensAddr, *citizensHost, *citizensPort))),
+			SessionService:    auth.NewAuthServiceClient(dial(palermoBackend, forward, target(*palermoAddr, *palermoHost, *palermoPort))),
+			TalkService:       talks.NewTalkingClient(dial(platoBackend, forward, target(*platoAddr, *platoHost, *platoPort))),
+			AssistantsService: assistants.NewAssistantsClient(dial(heleniaBackend, forward, target(*heleniaAddr, *heleniaHost, *heleniaPort))),
+		}
+	}
+
+	// graphql schemas
+	switch *auditSink {
+	case "none":
+	case "stdout":
+		graphCtx.Audit = audit.NewStdoutSink(*auditKeep)
+	case "file":
+		sink, err := audit.NewFileSink(*auditFile, *auditMaxSize, *auditMaxFiles)
+		check("audit:", err)
+		closeOnExit(sink)
+		graphCtx.Audit = sink
+	default:
+		check("audit:", fmt.Errorf("unknown sink %q", *auditSink))
+	}
+
+	s, err := schema.New(graphCtx)
 	check("session schema:", err)
 
 	mux := http.NewServeMux()
 
 	// public endpoint
-	mux.Handle("/", home.Routes())
+	homeRoutes, err := home.Routes(*graphiql)
+	check("graphiql:", err)
+	mux.Handle("/", homeRoutes)
 	mux.Handle("/metrics", prometheus.Routes())
-	mux.Handle("/healthz", healthz.Routes())
+	health := healthz.Routes(components...)
+	mux.Handle("/healthz", health)
+	mux.Handle("/readyz", health)
 
 	// private endpoint
 	ac := &api.Context{
-		User:    citizenSvc,
-		Session: palermoSvc,
-		Schema:  schema,
+		User:    graphCtx.UserService,
+		Session: graphCtx.SessionService,
+		Schema:  s,
+	}
+	if *idempotencyTTL > 0 {
+		ac.Idempotency = api.NewMemoryIdempotencyStore(*idempotencyTTL)
+	}
+	if *responseCacheSize > 0 {
+		ac.Cache = api.NewMemoryResponseCache(*responseCacheSize)
+	}
+	if *sessionCacheSize > 0 && *sessionCacheTTL > 0 {
+		ac.Sessions = firewall.NewSessionCache(*sessionCacheSize, *sessionCacheTTL, *sessionCacheNegativeTTL)
+	}
+
+	if *apiKeys != "" {
+		ac.APIKeys, err = firewall.LoadAPIKeys(*apiKeys)
+		check("api keys:", err)
 	}
 
-	mux.Handle("/graphql", api.Routes(ac))
+	if *jwtJWKS != "" {
+		if *jwtIssuer == "" || *jwtAudience == "" {
+			check("jwt:", errors.New("-jwt-issuer and -jwt-audience are required with -jwt-jwks"))
+		}
 
-	log.Println("Now server is running on port 3000")
-	n := negroni.New(negroni.NewRecovery(), negroni.NewLogger())
-	n.UseHandler(mux)
+		keys, err := firewall.NewKeySet(*jwtJWKS)
+		check("jwks:", err)
+		go watch.Files("jwks", keys, *jwtReloadInterval, nil)
 
-	check("server: ", http.ListenAndServe(":3000", n))
+		ac.JWT = firewall.NewJWTVerifier(keys, firewall.JWTOptions{
+			Issuer:     *jwtIssuer,
+			Audience:   *jwtAudience,
+			Leeway:     *jwtLeeway,
+			RolesClaim: *jwtRolesClaim,
+		})
+	}
+
+	for _, id := range strings.Split(*adminUsers, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ac.Admins = append(ac.Admins, id)
+		}
+	}
+	ac.AdminRole = *adminRole
+
+	if *csrf {
+		ac.CSRF = &firewall.CSRF{DoubleSubmit: *csrfDoubleSubmit}
+		if *csrfAllowedOrigins != "" {
+			ac.CSRF.AllowedOrigins = strings.Split(*csrfAllowedOrigins, ",")
+		}
+	}
+
+	api.Mount(mux, ac)
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: api.Middleware(logger, mux),
+	}
+
+	serve := srv.ListenAndServe
+	if *tlsCert != "" {
+		reloader, err := certs.NewReloader(*tlsCert, *tlsKey)
+		check("tls certificate:", err)
+		go watch.Files("certs", reloader, *tlsReloadInterval, nil)
+
+		srv.TLSConfig, err = certs.Config(reloader, certs.Options{
+			MinVersion:        *tlsMinVersion,
+			ClientCAFile:      *tlsClientCA,
+			RequireClientCert: *tlsRequireClientCert,
+		})
+		check("tls config:", err)
+
+		serve = func() error { return srv.ListenAndServeTLS("", "") }
+	}
+
+	slog.Info("server running", "port", *port, "tls", *tlsCert != "")
+	served := make(chan error, 1)
+	go func() { served <- serve() }()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	var serveErr error
+	select {
+	case serveErr = <-served:
+		slog.Error("server: " + serveErr.Error())
+	case sig := <-stop:
+		slog.Info("shutting down", "signal", sig.String())
+	}
+
+	// Let the requests in flight finish, then flush the spans they recorded.
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("server: shutdown: " + err.Error())
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("tracing: shutdown: " + err.Error())
+	}
+
+	closeAll()
+	if serveErr != nil {
+		os.Exit(1)
+	}
+}
+
+// dial connects to the service at addr through b, forwarding the identity
+// of the callers as configured by forward.
+func dial(b *backend.Backend, forward firewall.ForwardOptions, target string) *grpc.ClientConn {
+	slog.Info("connecting", "service", b.Name(), "target", target)
+	conn, err := api.Dial(b, forward, target)
+	check(b.Name()+" connection:", err)
+	return conn
+}
+
+// closers are closed before the process exits, os.Exit skipping the
+// deferred calls.
+var closers []io.Closer
+
+func closeOnExit(c io.Closer) {
+	closers = append(closers, c)
+}
+
+// closeAll closes the closers, the last added first.
+func closeAll() {
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			slog.Error("close: " + err.Error())
+		}
+	}
+	closers = nil
 }
 
 func check(section string, err error) {
 	if err != nil {
-		log.Fatal(fmt.Errorf("%s %v", section, err))
+		slog.Error(section + " " + err.Error())
+		closeAll()
+		os.Exit(1)
 	}
 }
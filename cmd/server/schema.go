@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/sicily/graph/schema"
+)
+
+const schemaUsage = `usage:
+  sicily schema print            print the schema SDL
+  sicily schema diff old.graphql compare the schema with a previous SDL snapshot`
+
+// runSchema implements the schema subcommands, the schema is built without
+// connecting to the backend services.
+func runSchema(args []string) error {
+	if len(args) == 0 {
+		return errors.New(schemaUsage)
+	}
+
+	s, err := schema.New(&graph.Context{})
+	if err != nil {
+		return err
+	}
+	sdl := schema.Print(s)
+
+	switch args[0] {
+	case "print":
+		fmt.Print(sdl)
+		return nil
+	case "diff":
+		if len(args) != 2 {
+			return errors.New(schemaUsage)
+		}
+
+		old, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			return err
+		}
+
+		changes, err := schema.Diff(string(old), sdl)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Println("No changes")
+			return nil
+		}
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+		if schema.HasBreaking(changes) {
+			return errors.New("breaking changes found")
+		}
+		return nil
+	default:
+		return errors.New(schemaUsage)
+	}
+}
@@ -0,0 +1,62 @@
+// Package watch reloads values loaded from files whenever the files change
+// on disk or the process receives SIGHUP.
+package watch
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Reloadable is a value loaded from files on disk.
+type Reloadable interface {
+	// Reload reads the files again, keeping the current value if they
+	// cannot be loaded.
+	Reload() error
+	// ModTime returns the last modification of the files.
+	ModTime() (time.Time, error)
+}
+
+// Files reloads r on SIGHUP and every time its files change, it checks for
+// changes each interval until done is closed. name prefixes the log lines.
+func Files(name string, r Reloadable, interval time.Duration, done <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := r.ModTime()
+	for {
+		select {
+		case <-done:
+			return
+		case <-hup:
+			last = reload(name, r, "SIGHUP", last)
+		case <-ticker.C:
+			if modTime, err := r.ModTime(); err == nil && !modTime.Equal(last) {
+				last = reload(name, r, "file change", last)
+			}
+		}
+	}
+}
+
+// reload reloads r and returns the modification time of the files loaded,
+// last when they could not be.
+func reload(name string, r Reloadable, reason string, last time.Time) time.Time {
+	// Read before reloading, so a change made meanwhile is reloaded again.
+	modTime, err := r.ModTime()
+	if err == nil {
+		err = r.Reload()
+	}
+	if err != nil {
+		slog.Warn(name+": reload failed, keeping current files", "reason", reason, "error", err)
+		return last
+	}
+
+	slog.Info(name+": reloaded", "reason", reason)
+	return modTime
+}
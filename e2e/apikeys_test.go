@@ -0,0 +1,86 @@
+package e2e_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/e2e"
+)
+
+func apiKeys(t *testing.T) firewall.APIKeyStore {
+	t.Helper()
+
+	keys, err := firewall.NewMemoryAPIKeyStore([]firewall.APIKey{
+		{Hash: firewall.HashAPIKey("reports-key"), Principal: firewall.Principal{Name: "reports", Scopes: []string{firewall.ScopeRead}, UserID: "user-2"}},
+		{Hash: firewall.HashAPIKey("mailer-key"), Principal: firewall.Principal{Name: "mailer", Scopes: []string{firewall.ScopeRead}, UserID: "user-2"}},
+		{Hash: firewall.HashAPIKey("admin-key"), Principal: firewall.Principal{Name: "admin", Scopes: []string{firewall.ScopeRead, firewall.ScopeWrite}, UserID: "user-1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func withAPIKey(query, key string) *e2e.Request {
+	return &e2e.Request{
+		Query:  query,
+		Header: http.Header{"X-API-Key": {key}},
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{APIKeys: apiKeys(t)})
+
+	query := `{ talks { id } }`
+	mutation := `mutation { cancelTalk(id: "` + gid("Talk", "talk-1") + `") { id } }`
+
+	tests := []struct {
+		name   string
+		key    string
+		query  string
+		status int
+	}{
+		{"query with read", "reports-key", query, http.StatusOK},
+		{"mutation with read", "reports-key", mutation, http.StatusForbidden},
+		{"mutation with write", "admin-key", mutation, http.StatusOK},
+		{"unknown key", "unknown-key", query, http.StatusUnauthorized},
+		{"hash of a key", firewall.HashAPIKey("reports-key"), query, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := h.Do(t, withAPIKey(tt.query, tt.key))
+			res.AssertStatus(t, tt.status)
+			if tt.status == http.StatusOK {
+				res.AssertNoErrors(t)
+			}
+		})
+	}
+
+	// No palermo session is looked up for services.
+	h.AssertCalled(t, "AuthService/Get", 0)
+
+	// Without a store every key is refused.
+	h = e2e.New(t, fixtures())
+	h.Do(t, withAPIKey(query, "reports-key")).AssertStatus(t, http.StatusUnauthorized)
+}
+
+func TestAPIKeysPrivateCache(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{APIKeys: apiKeys(t), CacheSize: 100})
+	query := `{ talk(id: "` + gid("Talk", "talk-1") + `") { viewerRegistration { id } } }`
+
+	res := h.Do(t, withAPIKey(query, "reports-key"))
+	res.AssertNoErrors(t)
+	assertCache(t, res, "MISS", "private, max-age=30")
+	if got := h.Do(t, withAPIKey(query, "reports-key")).Header.Get("X-Cache"); got != "HIT" {
+		t.Fatalf("X-Cache = %q, want HIT", got)
+	}
+
+	// Services acting as the same user keep their own private results, as
+	// does the user.
+	assertCache(t, h.Do(t, withAPIKey(query, "mailer-key")), "MISS", "private, max-age=30")
+	res = h.Query(t, h.Session("user-2"), query)
+	res.AssertNoErrors(t)
+	assertCache(t, res, "MISS", "private, max-age=30")
+}
@@ -0,0 +1,132 @@
+package e2e_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-toschool/sicily/cmd/server/backend"
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/cmd/server/logging"
+	"github.com/go-toschool/sicily/e2e"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackendRetries(t *testing.T) {
+	opts := backend.DefaultOptions
+	opts.Retries, opts.Backoff, opts.FailureThreshold = 2, time.Millisecond, 0
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{Backend: &opts})
+	ada := h.Session("user-1")
+	unavailable := status.Error(codes.Unavailable, "plato is down")
+
+	// Get and Select are attempted once more per retry.
+	h.Fail("Talking/Get", unavailable)
+	res := h.Query(t, ada, `{ talk(id: "`+gid("Talk", "talk-1")+`") { title } }`)
+	assertCode(t, res, "UNAVAILABLE")
+	h.AssertCalled(t, "Talking/Get", 3)
+
+	// Errors that do not go away by retrying are returned at once.
+	h.ResetCalls()
+	h.Fail("Talking/Get", status.Error(codes.NotFound, "no talk"))
+	res = h.Query(t, ada, `{ talk(id: "`+gid("Talk", "talk-1")+`") { title } }`)
+	assertCode(t, res, "NOT_FOUND")
+	h.AssertCalled(t, "Talking/Get", 1)
+
+	// Other methods may not be idempotent and are never retried.
+	h.Fail("Talking/Create", unavailable)
+	res = h.Query(t, ada, createTalk)
+	assertCode(t, res, "UNAVAILABLE")
+	h.AssertCalled(t, "Talking/Create", 1)
+}
+
+func TestBackendBreaker(t *testing.T) {
+	cooldown := 50 * time.Millisecond
+	opts := backend.DefaultOptions
+	opts.Retries, opts.FailureThreshold, opts.Cooldown = 0, 2, cooldown
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{Backend: &opts})
+	ada := h.Session("user-1")
+	plato := h.Backend("plato")
+	talk := func() *e2e.Response {
+		return h.Query(t, ada, `{ talk(id: "`+gid("Talk", "talk-1")+`") { title } }`)
+	}
+	assertState := func(want backend.State) {
+		t.Helper()
+		if got := plato.State(); got != want {
+			t.Fatalf("breaker %s, want %s", got, want)
+		}
+	}
+
+	h.Fail("Talking/Get", status.Error(codes.Unavailable, "plato is down"))
+	assertCode(t, talk(), "UNAVAILABLE")
+	assertState(backend.Closed)
+	assertCode(t, talk(), "UNAVAILABLE")
+	assertState(backend.Open)
+
+	// An open breaker fails the calls without reaching plato.
+	res := talk()
+	assertCode(t, res, "UNAVAILABLE")
+	res.AssertError(t, "plato is unavailable")
+	h.AssertCalled(t, "Talking/Get", 2)
+
+	// After the cooldown a failing trial call opens it again.
+	time.Sleep(cooldown)
+	assertCode(t, talk(), "UNAVAILABLE")
+	h.AssertCalled(t, "Talking/Get", 3)
+	assertState(backend.Open)
+
+	// A successful trial call closes it.
+	h.Fail("Talking/Get", nil)
+	time.Sleep(cooldown)
+	talk().AssertNoErrors(t)
+	assertState(backend.Closed)
+	talk().AssertNoErrors(t)
+	h.AssertCalled(t, "Talking/Get", 5)
+}
+
+func TestBackendForwarding(t *testing.T) {
+	forward := firewall.DefaultForwardOptions
+	forward.TokenServices = []string{"plato"}
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{Forward: &forward})
+	ada := h.Session("user-1")
+
+	res := h.Query(t, ada, `{ talk(id: "`+gid("Talk", "talk-1")+`") { speaker { full_name } } }`)
+	res.AssertNoErrors(t)
+	requestID := res.Header.Get(logging.RequestIDHeader)
+
+	get := h.Calls("Talking/Get")
+	if len(get) != 1 {
+		t.Fatalf("Talking/Get called %d times", len(get))
+	}
+	md := get[0].Metadata
+	for key, want := range map[string]string{
+		logging.RequestIDMetaKey: requestID,
+		forward.UserIDKey:        "user-1",
+		forward.SessionIDKey:     ada.ID,
+		forward.TokenKey:         ada.AuthToken,
+	} {
+		if got := md.Get(key); len(got) != 1 || got[0] != want {
+			t.Errorf("plato got %s = %v, want %q", key, got, want)
+		}
+	}
+
+	// The token is only sent to the services configured.
+	speaker := h.Calls("Citizenship/Get")
+	if len(speaker) == 0 {
+		t.Fatal("Citizenship/Get not called")
+	}
+	md = speaker[len(speaker)-1].Metadata
+	if got := md.Get(forward.TokenKey); len(got) != 0 {
+		t.Errorf("citizens got the auth token: %v", got)
+	}
+	if got := md.Get(forward.UserIDKey); len(got) != 1 || got[0] != "user-1" {
+		t.Errorf("citizens got %s = %v", forward.UserIDKey, got)
+	}
+
+	// Calls made without a caller, as the session check, carry no identity.
+	h.AssertCalled(t, "AuthService/Get", 1)
+	for _, c := range h.Calls("AuthService/Get") {
+		if got := c.Metadata.Get(forward.UserIDKey); len(got) != 0 {
+			t.Errorf("palermo got %s = %v", forward.UserIDKey, got)
+		}
+	}
+}
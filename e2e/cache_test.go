@@ -0,0 +1,139 @@
+package e2e_test
+
+import (
+	"testing"
+
+	"github.com/go-toschool/sicily/e2e"
+)
+
+func TestCacheSessionNode(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{CacheSize: 100})
+	ada := h.Session("user-1")
+	query := `{ node(id: "` + gid("Session", ada.ID) + `") { ... on Session { token } } }`
+
+	res := h.Query(t, ada, query)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "node.token", ada.AuthToken)
+	if got := res.Header.Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("Cache-Control = %q, want no-store", got)
+	}
+
+	// Grace sends the same document and must not get the session of Ada.
+	res = h.Query(t, h.Session("user-2"), query)
+	if got := res.Header.Get("X-Cache"); got == "HIT" {
+		t.Fatalf("session of another user served from the cache: %s", res.Body)
+	}
+	res.AssertData(t, "node", nil)
+}
+
+func assertCache(t *testing.T, res *e2e.Response, xCache, cacheControl string) {
+	t.Helper()
+
+	if got := res.Header.Get("X-Cache"); got != xCache {
+		t.Fatalf("X-Cache = %q, want %q; body: %s", got, xCache, res.Body)
+	}
+	if got := res.Header.Get("Cache-Control"); got != cacheControl {
+		t.Fatalf("Cache-Control = %q, want %q", got, cacheControl)
+	}
+}
+
+func TestCachePublicQuery(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{CacheSize: 100})
+	query := `{ talks { id title } }`
+
+	res := h.Query(t, h.Session("user-1"), query)
+	res.AssertNoErrors(t)
+	assertCache(t, res, "MISS", "public, max-age=60")
+
+	// Public results are served to every caller without reaching platon.
+	h.ResetCalls()
+	res = h.Query(t, h.Session("user-2"), query)
+	res.AssertNoErrors(t)
+	if got := res.Header.Get("X-Cache"); got != "HIT" {
+		t.Fatalf("X-Cache = %q, want HIT", got)
+	}
+	res.AssertData(t, "talks.0.title", "Intro to gRPC")
+	h.AssertCalled(t, "Talking/Select", 0)
+}
+
+func TestCacheControl(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{CacheSize: 100})
+	ada := h.Session("user-1")
+
+	// The shortest hint wins: talks are cached a minute, assistants 30s.
+	res := h.Query(t, ada, `{ talks { id attendees { id } } }`)
+	res.AssertNoErrors(t)
+	assertCache(t, res, "MISS", "public, max-age=30")
+
+	// A private field makes the whole result private.
+	res = h.Query(t, ada, `{ talks { id viewerRegistration { id } } }`)
+	res.AssertNoErrors(t)
+	assertCache(t, res, "MISS", "private, max-age=60")
+
+	res = h.Query(t, ada, `mutation { cancelTalk(id: "`+gid("Talk", "talk-1")+`") { id } }`)
+	res.AssertNoErrors(t)
+	assertCache(t, res, "", "no-store")
+}
+
+func TestCachePrivateQuery(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{CacheSize: 100})
+	query := `{ talk(id: "` + gid("Talk", "talk-1") + `") { viewerRegistration { id } } }`
+
+	res := h.Query(t, h.Session("user-2"), query)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "talk.viewerRegistration.id", gid("Assistant", "assistant-1"))
+	assertCache(t, res, "MISS", "private, max-age=30")
+
+	res = h.Query(t, h.Session("user-2"), query)
+	if got := res.Header.Get("X-Cache"); got != "HIT" {
+		t.Fatalf("X-Cache = %q, want HIT", got)
+	}
+
+	// Linus is not registered and must not get the registration of Grace.
+	res = h.Query(t, h.Session("user-3"), query)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "talk.viewerRegistration", nil)
+	assertCache(t, res, "MISS", "private, max-age=60")
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{CacheSize: 100})
+	ada := h.Session("user-1")
+
+	// query caches the result of document and returns it once served from
+	// the cache, so the mutations below are checked against a cached copy.
+	query := func(document string) *e2e.Response {
+		h.Query(t, ada, document).AssertNoErrors(t)
+		res := h.Query(t, ada, document)
+		if got := res.Header.Get("X-Cache"); got != "HIT" {
+			t.Fatalf("X-Cache = %q, want HIT; body: %s", got, res.Body)
+		}
+		return res
+	}
+
+	talks := `{ talks { title attendeeCount } }`
+	query(talks)
+	h.Query(t, ada, `mutation {
+		createTalk(input: {title: "Go tooling", date: "2019-05-01T18:00:00Z"}) { id }
+	}`).AssertNoErrors(t)
+	res := h.Query(t, ada, talks)
+	assertCache(t, res, "MISS", "public, max-age=60")
+	res.AssertData(t, "talks.2.title", "Go tooling")
+
+	query(talks)
+	h.Query(t, h.Session("user-3"), `mutation {
+		registerTalk(talk_id: "`+gid("Talk", "talk-1")+`") { assistant { id } }
+	}`).AssertNoErrors(t)
+	res = h.Query(t, ada, talks)
+	assertCache(t, res, "MISS", "public, max-age=60")
+	res.AssertData(t, "talks.0.attendeeCount", 2)
+
+	users := `{ users { full_name } }`
+	query(users)
+	h.Query(t, ada, `mutation {
+		updateUser(id: "`+gid("User", "user-1")+`", full_name: "Ada King") { id }
+	}`).AssertNoErrors(t)
+	res = h.Query(t, ada, users)
+	assertCache(t, res, "MISS", "public, max-age=60")
+	res.AssertData(t, "users.0.full_name", "Ada King")
+}
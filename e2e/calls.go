@@ -0,0 +1,101 @@
+package e2e
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Call is a request received by one of the fake gRPC servers.
+type Call struct {
+	// Method is the full gRPC method name, e.g. "/talks.Talking/Get".
+	Method  string
+	Request interface{}
+	// Metadata is the gRPC metadata sent by the gateway with the call.
+	Metadata metadata.MD
+}
+
+// failure is an error injected into the calls to method.
+type failure struct {
+	method string
+	err    error
+}
+
+// recorder keeps the calls received by the fake servers and the failures
+// injected into them, in the order they were added.
+type recorder struct {
+	mu       sync.Mutex
+	calls    []Call
+	failures []failure
+}
+
+func newRecorder() *recorder {
+	return &recorder{}
+}
+
+func (r *recorder) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	r.mu.Lock()
+	r.calls = append(r.calls, Call{Method: info.FullMethod, Request: req, Metadata: md})
+	var err error
+	for i := len(r.failures) - 1; i >= 0; i-- {
+		if matches(info.FullMethod, r.failures[i].method) {
+			err = r.failures[i].err
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (r *recorder) fail(method string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, f := range r.failures {
+		if f.method == method {
+			r.failures = append(r.failures[:i], r.failures[i+1:]...)
+			break
+		}
+	}
+	if err != nil {
+		r.failures = append(r.failures, failure{method, err})
+	}
+}
+
+func (r *recorder) find(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]Call, 0)
+	for _, c := range r.calls {
+		if matches(c.Method, method) {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+func (r *recorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+}
+
+// matches reports whether a full gRPC method name ends with method, so
+// callers can write "Talking/Get" instead of the fully qualified name. An
+// empty method matches every call.
+func matches(fullMethod, method string) bool {
+	return strings.HasSuffix(fullMethod, method)
+}
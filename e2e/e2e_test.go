@@ -0,0 +1,414 @@
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily/cmd/server/audit"
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/e2e"
+	"github.com/go-toschool/sicily/graph/types"
+	"github.com/go-toschool/sicily/mock"
+	"github.com/go-toschool/syracuse/citizens"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fixtures seeds talk-1, given by Ada with two seats, one of them held by
+// Grace. Linus gives talk-2 and registered into nothing.
+func fixtures() *mock.Fixtures {
+	return &mock.Fixtures{
+		Citizens: []*citizens.Citizen{
+			{Id: "user-1", Email: "ada@example.com", FullName: "Ada Lovelace", Token: "ada-token", CreatedAt: 1550000001, UpdatedAt: 1550000002},
+			{Id: "user-2", Email: "grace@example.com", FullName: "Grace Hopper"},
+			{Id: "user-3", Email: "linus@example.com", FullName: "Linus Torvalds"},
+		},
+		Talks: []*talks.Talk{
+			{
+				Id:          "talk-1",
+				Title:       "Intro to gRPC",
+				Description: "Services over HTTP/2",
+				Repository:  "https://github.com/go-toschool/grpc",
+				Date:        1552586400,
+				Tags:        "go, grpc",
+				UserId:      "user-1",
+				Capacity:    2,
+				CreatedAt:   1550000003,
+				UpdatedAt:   1550000004,
+			},
+			{Id: "talk-2", Title: "Kernels", Date: 1552590000, UserId: "user-3"},
+		},
+		Assistants: []*assistants.Assistant{
+			{Id: "assistant-1", Speaker: "user-1", Assistant: "user-2", TalkId: "talk-1", Status: types.StatusConfirmed, CreatedAt: 1550000005, UpdatedAt: 1550000006},
+		},
+	}
+}
+
+func gid(typeName, id string) string {
+	return types.GlobalID(typeName, id)
+}
+
+func assertCode(t *testing.T, res *e2e.Response, code string) {
+	t.Helper()
+
+	for _, e := range res.Errors {
+		if e.Extensions["code"] == code {
+			return
+		}
+	}
+	t.Fatalf("no error with code %s in: %s", code, res.Body)
+}
+
+func TestTalksQueries(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	ada := h.Session("user-1")
+
+	res := h.Query(t, ada, `{ talks { id title } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "talks", []map[string]string{
+		{"id": gid("Talk", "talk-1"), "title": "Intro to gRPC"},
+		{"id": gid("Talk", "talk-2"), "title": "Kernels"},
+	})
+
+	res = h.Query(t, ada, `{ talk(id: "`+gid("Talk", "talk-2")+`") { title } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "talk.title", "Kernels")
+
+	res = h.Query(t, ada, `{ talk(id: "`+gid("Talk", "talk-9")+`") { title } }`)
+	res.AssertData(t, "talk", nil)
+	assertCode(t, res, "NOT_FOUND")
+}
+
+func TestTalkFields(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	grace := h.Session("user-2")
+
+	res := h.Query(t, grace, `{
+		talk(id: "`+gid("Talk", "talk-1")+`") {
+			id title description repository date tags capacity cancelled
+			cancel_reason created_at updated_at attendeeCount
+			speaker { id full_name }
+			attendees { id talk_id user_id speaker registrationStatus created_at updated_at }
+			waitlist { id }
+			viewerRegistration { id }
+		}
+	}`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "talk", map[string]interface{}{
+		"id":            gid("Talk", "talk-1"),
+		"title":         "Intro to gRPC",
+		"description":   "Services over HTTP/2",
+		"repository":    "https://github.com/go-toschool/grpc",
+		"date":          "2019-03-14T18:00:00Z",
+		"tags":          []string{"go", "grpc"},
+		"capacity":      2,
+		"cancelled":     false,
+		"cancel_reason": "",
+		"created_at":    "2019-02-12T19:33:23Z",
+		"updated_at":    "2019-02-12T19:33:24Z",
+		"attendeeCount": 1,
+		"speaker":       map[string]string{"id": gid("User", "user-1"), "full_name": "Ada Lovelace"},
+		"attendees": []map[string]string{{
+			"id":                 gid("Assistant", "assistant-1"),
+			"talk_id":            gid("Talk", "talk-1"),
+			"user_id":            gid("User", "user-2"),
+			"speaker":            gid("User", "user-1"),
+			"registrationStatus": "CONFIRMED",
+			"created_at":         "2019-02-12T19:33:25Z",
+			"updated_at":         "2019-02-12T19:33:26Z",
+		}},
+		"waitlist":           []string{},
+		"viewerRegistration": map[string]string{"id": gid("Assistant", "assistant-1")},
+	})
+
+	// The assistants of the talk are loaded once for every field using them.
+	h.AssertCalled(t, "Assistants/Select", 1)
+}
+
+func TestUserQueries(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	ada := h.Session("user-1")
+
+	res := h.Query(t, ada, `{ user { id email full_name token created_at updated_at talks { id } } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "user", map[string]interface{}{
+		"id":         gid("User", "user-1"),
+		"email":      "ada@example.com",
+		"full_name":  "Ada Lovelace",
+		"token":      "ada-token",
+		"created_at": "2019-02-12T19:33:21Z",
+		"updated_at": "2019-02-12T19:33:22Z",
+		"talks":      []map[string]string{{"id": gid("Talk", "talk-1")}},
+	})
+
+	// user resolves the caller, other users are read through node.
+	res = h.Query(t, ada, `{ node(id: "`+gid("User", "user-3")+`") { ... on User { full_name talks { title } } } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "node.full_name", "Linus Torvalds")
+	res.AssertData(t, "node.talks", []map[string]string{{"title": "Kernels"}})
+
+	res = h.Query(t, ada, `{ users { id } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "users", []map[string]string{
+		{"id": gid("User", "user-1")},
+		{"id": gid("User", "user-2")},
+		{"id": gid("User", "user-3")},
+	})
+}
+
+func TestNodeQueries(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	ada := h.Session("user-1")
+
+	res := h.Query(t, ada, `{
+		talk: node(id: "`+gid("Talk", "talk-1")+`") { id ... on Talk { title } }
+		user: node(id: "`+gid("User", "user-2")+`") { ... on User { full_name } }
+		assistant: node(id: "`+gid("Assistant", "assistant-1")+`") { ... on Assistant { registrationStatus } }
+		session: node(id: "`+gid("Session", ada.ID)+`") { ... on Session { user_id } }
+	}`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "talk", map[string]string{"id": gid("Talk", "talk-1"), "title": "Intro to gRPC"})
+	res.AssertData(t, "user.full_name", "Grace Hopper")
+	res.AssertData(t, "assistant.registrationStatus", "CONFIRMED")
+	res.AssertData(t, "session.user_id", gid("User", "user-1"))
+
+	// Each id resolves on its own, a missing object does not fail the others.
+	res = h.Query(t, ada, `{
+		nodes(ids: ["`+gid("Talk", "talk-2")+`", "`+gid("Talk", "talk-9")+`", "`+gid("User", "user-3")+`"]) {
+			id
+		}
+	}`)
+	res.AssertData(t, "nodes", []interface{}{
+		map[string]string{"id": gid("Talk", "talk-2")},
+		nil,
+		map[string]string{"id": gid("User", "user-3")},
+	})
+	assertCode(t, res, "NOT_FOUND")
+}
+
+func TestAuditLog(t *testing.T) {
+	sink := audit.NewWriterSink(io.Discard, 10)
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{
+		Admins: []string{"user-1"},
+		Audit:  sink,
+	})
+
+	res := h.Query(t, h.Session("user-1"), `mutation { cancelTalk(id: "`+gid("Talk", "talk-1")+`", reason: "Sick") { id } }`)
+	res.AssertNoErrors(t)
+
+	res = h.Query(t, h.Session("user-1"), `{ audit_log(limit: 5) { mutation outcome user_id target_ids } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "audit_log", []map[string]interface{}{{
+		"mutation":   "cancelTalk",
+		"outcome":    "success",
+		"user_id":    "user-1",
+		"target_ids": []string{gid("Talk", "talk-1")},
+	}})
+
+	res = h.Query(t, h.Session("user-2"), `{ audit_log { mutation } }`)
+	assertCode(t, res, "PERMISSION_DENIED")
+}
+
+func TestTalkMutations(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	ada := h.Session("user-1")
+	grace := h.Session("user-2")
+	linus := h.Session("user-3")
+
+	res := h.Query(t, ada, `mutation {
+		createTalk(input: {title: "Go tooling", date: "2019-05-01T18:00:00Z", capacity: 1, tags: ["go"]}) {
+			id title date capacity tags speaker { id }
+		}
+	}`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "createTalk.title", "Go tooling")
+	res.AssertData(t, "createTalk.date", "2019-05-01T18:00:00Z")
+	res.AssertData(t, "createTalk.capacity", 1)
+	res.AssertData(t, "createTalk.tags", []string{"go"})
+	res.AssertData(t, "createTalk.speaker.id", gid("User", "user-1"))
+	id, _ := res.Get("createTalk.id")
+	talkID := id.(string)
+
+	res = h.Query(t, ada, `mutation {
+		updateTalk(id: "`+talkID+`", patch: {title: "Go tools", tags: [], capacity: 2}) { title tags capacity }
+	}`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "updateTalk", map[string]interface{}{"title": "Go tools", "tags": []string{}, "capacity": 2})
+
+	res = h.Query(t, grace, `mutation { updateTalk(id: "`+talkID+`", patch: {title: "Mine"}) { title } }`)
+	res.AssertError(t, "Only the speaker can change this talk")
+
+	res = h.Query(t, ada, `mutation { cancelTalk(id: "`+talkID+`", reason: "Sick") { cancelled cancel_reason } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "cancelTalk", map[string]interface{}{"cancelled": true, "cancel_reason": "Sick"})
+
+	res = h.Query(t, ada, `mutation { updateTalk(id: "`+talkID+`", patch: {title: "Back"}) { title } }`)
+	res.AssertError(t, "Talk is cancelled")
+
+	res = h.Query(t, linus, `mutation { registerTalk(talk_id: "`+gid("Talk", "talk-1")+`") { assistant { id } } }`)
+	res.AssertNoErrors(t)
+
+	// Deleting a talk deletes its registrations.
+	res = h.Query(t, ada, `mutation { deleteTalk(id: "`+gid("Talk", "talk-1")+`") { id } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "deleteTalk.id", gid("Talk", "talk-1"))
+
+	left, err := h.Backends.Assistants.Select(context.Background(), &assistants.SelectRequest{TalkId: "talk-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(left.GetData()); n != 0 {
+		t.Fatalf("%d registrations left after deleting the talk", n)
+	}
+
+	res = h.Query(t, ada, `{ talk(id: "`+gid("Talk", "talk-1")+`") { id } }`)
+	assertCode(t, res, "NOT_FOUND")
+}
+
+func TestRegistrationMutations(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	talkID := gid("Talk", "talk-1")
+
+	// Grace holds one of the two seats.
+	res := h.Query(t, h.Session("user-3"), `mutation {
+		registerTalk(talk_id: "`+talkID+`") { talk { attendeeCount } assistant { user_id registrationStatus } }
+	}`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "registerTalk.talk.attendeeCount", 2)
+	res.AssertData(t, "registerTalk.assistant.registrationStatus", "CONFIRMED")
+
+	res = h.Query(t, h.Session("user-1"), `mutation {
+		registerTalk(talk_id: "`+talkID+`") { talk { waitlist { user_id } } assistant { registrationStatus } }
+	}`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "registerTalk.assistant.registrationStatus", "WAITLISTED")
+	res.AssertData(t, "registerTalk.talk.waitlist", []map[string]string{{"user_id": gid("User", "user-1")}})
+
+	// Grace leaving gives her seat to Ada.
+	res = h.Query(t, h.Session("user-2"), `mutation {
+		unregisterTalk(talk_id: "`+talkID+`") { talk { attendees { user_id } waitlist { id } } }
+	}`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "unregisterTalk.talk.waitlist", []string{})
+	res.AssertData(t, "unregisterTalk.talk.attendees", []map[string]string{
+		{"user_id": gid("User", "user-3")},
+		{"user_id": gid("User", "user-1")},
+	})
+
+	res = h.Query(t, h.Session("user-2"), `mutation { unregisterTalk(talk_id: "`+talkID+`") { talk { id } } }`)
+	res.AssertError(t, "User is not registered into this talk")
+
+	// Only admins and services register other users.
+	res = h.Query(t, h.Session("user-2"), `mutation {
+		registerTalk(talk_id: "`+gid("Talk", "talk-2")+`", user_id: "`+gid("User", "user-3")+`") { assistant { id } }
+	}`)
+	assertCode(t, res, "PERMISSION_DENIED")
+}
+
+func TestUserMutations(t *testing.T) {
+	h := e2e.New(t, fixtures())
+
+	res := h.Query(t, h.Session("user-1"), `mutation {
+		updateUser(id: "`+gid("User", "user-1")+`", full_name: "Ada King") { id full_name }
+	}`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "updateUser", map[string]string{"id": gid("User", "user-1"), "full_name": "Ada King"})
+
+	res = h.Query(t, h.Session("user-1"), `{ user { full_name } }`)
+	res.AssertData(t, "user.full_name", "Ada King")
+}
+
+func TestBackendFailures(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	ada := h.Session("user-1")
+	talkID := gid("Talk", "talk-1")
+
+	// A failing service nulls the fields it resolves, the others are kept.
+	h.Fail("Assistants/Select", status.Error(codes.Unavailable, "helenia is down"))
+	res := h.Query(t, ada, `{ talk(id: "`+talkID+`") { title attendees { id } } }`)
+	res.AssertData(t, "talk.title", "Intro to gRPC")
+	res.AssertData(t, "talk.attendees", nil)
+	assertCode(t, res, "UNAVAILABLE")
+	h.Fail("Assistants/Select", nil)
+
+	// The failure added last wins over the ones added before, adding one
+	// again makes it the last.
+	talk := func() *e2e.Response {
+		return h.Query(t, ada, `{ talk(id: "`+talkID+`") { title } }`)
+	}
+	h.Fail("Talking/Get", status.Error(codes.DeadlineExceeded, "too slow"))
+	h.Fail("/talks.Talking/Get", status.Error(codes.NotFound, "no talk"))
+	for i := 0; i < 5; i++ {
+		assertCode(t, talk(), "NOT_FOUND")
+	}
+
+	h.Fail("Talking/Get", status.Error(codes.DeadlineExceeded, "too slow"))
+	assertCode(t, talk(), "DEADLINE_EXCEEDED")
+
+	h.Fail("Talking/Get", nil)
+	assertCode(t, talk(), "NOT_FOUND")
+
+	h.Fail("/talks.Talking/Get", nil)
+	talk().AssertNoErrors(t)
+}
+
+func TestAuthentication(t *testing.T) {
+	h := e2e.New(t, fixtures())
+
+	res := h.Query(t, nil, `{ talks { id } }`)
+	res.AssertStatus(t, http.StatusUnauthorized)
+
+	res = h.Query(t, &mock.Session{AuthToken: "forged", ValidationToken: "forged"}, `{ talks { id } }`)
+	res.AssertStatus(t, http.StatusUnauthorized)
+}
+
+func TestCSRF(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{
+		CSRF: &firewall.CSRF{AllowedOrigins: []string{"https://app.example.com"}},
+	})
+
+	for _, tt := range []struct {
+		header http.Header
+		want   int
+	}{
+		{http.Header{"Origin": {"https://app.example.com"}}, http.StatusOK},
+		{http.Header{"Origin": {"http://app.example.com"}}, http.StatusForbidden},
+		{http.Header{"Origin": {"https://evil.example.com"}}, http.StatusForbidden},
+		{http.Header{"Referer": {"https://evil.example.com/page"}}, http.StatusForbidden},
+		// A page hiding its referrer sends neither header.
+		{http.Header{}, http.StatusForbidden},
+		{http.Header{"X-Requested-With": {"XMLHttpRequest"}}, http.StatusOK},
+	} {
+		res := h.Do(t, &e2e.Request{
+			Query:   `mutation { cancelTalk(id: "` + gid("Talk", "talk-1") + `") { id } }`,
+			Session: h.Session("user-1"),
+			Header:  tt.header,
+		})
+		res.AssertStatus(t, tt.want)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{
+		Logger: slog.New(slog.NewJSONHandler(&logs, nil)),
+	})
+
+	res := h.Query(t, h.Session("user-1"), `query Talks { talks { id } }`)
+	res.AssertNoErrors(t)
+
+	requestID := res.Header.Get("X-Request-ID")
+	if requestID == "" {
+		t.Fatal("no X-Request-ID header")
+	}
+	if !strings.Contains(logs.String(), `"request_id":"`+requestID+`"`) {
+		t.Fatalf("request %s not logged: %s", requestID, logs.String())
+	}
+}
@@ -0,0 +1,315 @@
+// Package e2e drives the whole gateway in-process for end-to-end tests.
+//
+// A Harness serves the in-memory mock backends as gRPC servers on bufconn
+// listeners, connects the real GraphQL schema to them with the retries,
+// breakers and interceptors of the server and sends HTTP requests through the
+// same middleware, firewall and api stack:
+//
+//	h := e2e.New(t, &mock.Fixtures{...})
+//	res := h.Query(t, h.Session("user-1"), `{ talks { id title } }`)
+//	res.AssertNoErrors(t)
+//	res.AssertData(t, "talks.0.title", "Intro to gRPC")
+//	h.AssertCalled(t, "Talking/Select", 1)
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/palermo/auth"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily/cmd/server/api"
+	"github.com/go-toschool/sicily/cmd/server/backend"
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/sicily/graph/schema"
+	"github.com/go-toschool/sicily/mock"
+	"github.com/go-toschool/syracuse/citizens"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024
+
+// backendOptions are the options of the calls to the fake services unless
+// configured, the defaults of the gateway with a backoff short enough for
+// the retries of tests.
+var backendOptions = func() backend.Options {
+	o := backend.DefaultOptions
+	o.Backoff = time.Millisecond
+	return o
+}()
+
+// Harness is a gateway connected to fake backend services.
+type Harness struct {
+	// Backends holds the state of the fake services, it can be inspected or
+	// modified directly by tests.
+	Backends *mock.Backends
+	// Context is the graph context connected to the fake services.
+	Context *graph.Context
+	// Handler is the full HTTP stack of the gateway.
+	Handler http.Handler
+
+	calls    *recorder
+	backend  backend.Options
+	forward  firewall.ForwardOptions
+	backends map[string]*backend.Backend
+	servers  []*grpc.Server
+	conns    []*grpc.ClientConn
+}
+
+// Options configures the gateway of a harness, the zero value leaves the
+// optional features of the server off.
+type Options struct {
+	// Logger receives the request logs, nil discards them.
+	Logger *slog.Logger
+	// CSRF checks the requests authenticated by the access_token cookie, as
+	// the -csrf flags of the server.
+	CSRF *firewall.CSRF
+	// APIKeys authenticates services by their X-API-Key header.
+	APIKeys firewall.APIKeyStore
+	// Sessions keeps the sessions validated by the fake palermo.
+	Sessions *firewall.SessionCache
+	// JWT verifies the Bearer tokens that are JWTs locally.
+	JWT *firewall.JWTVerifier
+	// Admins are the ids of the users allowed to read the audit log.
+	Admins []string
+	// Audit receives the audit entries of the mutations.
+	Audit graph.AuditSink
+	// Idempotency stores the results of mutations by idempotency key, nil
+	// keeps them an hour in memory.
+	Idempotency api.IdempotencyStore
+	// CacheSize enables a response cache keeping that many results.
+	CacheSize int
+	// Backend configures the retries, timeouts and breakers of the calls to
+	// the fake services, nil uses the defaults of the gateway with a 1ms
+	// backoff.
+	Backend *backend.Options
+	// Forward configures the identity sent to the fake services, nil uses
+	// firewall.DefaultForwardOptions.
+	Forward *firewall.ForwardOptions
+}
+
+// Request is a GraphQL request sent through the harness.
+type Request struct {
+	Query string
+	// Session holds the credentials sent with the request, nil sends none.
+	Session *mock.Session
+	Header  http.Header
+}
+
+// New starts the fake services seeded with f and builds the gateway on top
+// of them, everything is stopped when the test finishes.
+func New(t testing.TB, f *mock.Fixtures) *Harness {
+	t.Helper()
+
+	return NewWithOptions(t, f, Options{})
+}
+
+// NewWithOptions is New with the gateway configured by o.
+func NewWithOptions(t testing.TB, f *mock.Fixtures, o Options) *Harness {
+	t.Helper()
+
+	h := &Harness{
+		Backends: mock.New(f),
+		calls:    newRecorder(),
+		backend:  backendOptions,
+		forward:  firewall.DefaultForwardOptions,
+	}
+	if o.Backend != nil {
+		h.backend = *o.Backend
+	}
+	if o.Forward != nil {
+		h.forward = *o.Forward
+	}
+	t.Cleanup(h.close)
+
+	h.Context = &graph.Context{
+		UserService: citizens.NewCitizenshipClient(h.serve(t, "citizens", func(s *grpc.Server) {
+			citizens.RegisterCitizenshipServer(s, &citizensServer{h.Backends.Citizens})
+		})),
+		TalkService: talks.NewTalkingClient(h.serve(t, "plato", func(s *grpc.Server) {
+			talks.RegisterTalkingServer(s, &talksServer{h.Backends.Talks})
+		})),
+		AssistantsService: assistants.NewAssistantsClient(h.serve(t, "helenia", func(s *grpc.Server) {
+			assistants.RegisterAssistantsServer(s, &assistantsServer{h.Backends.Assistants})
+		})),
+		SessionService: auth.NewAuthServiceClient(h.serve(t, "palermo", func(s *grpc.Server) {
+			auth.RegisterAuthServiceServer(s, &authServer{h.Backends.Sessions})
+		})),
+		Audit: o.Audit,
+	}
+
+	s, err := schema.New(h.Context)
+	if err != nil {
+		t.Fatalf("e2e: schema: %v", err)
+	}
+
+	ac := &api.Context{
+		User:        h.Context.UserService,
+		Session:     h.Context.SessionService,
+		Schema:      s,
+		Idempotency: o.Idempotency,
+		APIKeys:     o.APIKeys,
+		JWT:         o.JWT,
+		Sessions:    o.Sessions,
+		CSRF:        o.CSRF,
+		Admins:      o.Admins,
+	}
+	if ac.Idempotency == nil {
+		ac.Idempotency = api.NewMemoryIdempotencyStore(time.Hour)
+	}
+	if o.CacheSize > 0 {
+		ac.Cache = api.NewMemoryResponseCache(o.CacheSize)
+	}
+
+	logger := o.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	mux := http.NewServeMux()
+	api.Mount(mux, ac)
+	h.Handler = api.Middleware(logger, mux)
+
+	return h
+}
+
+// serve starts a gRPC server on an in-memory listener and returns a client
+// connection to it, made by the gateway as to the service called name.
+func (h *Harness) serve(t testing.TB, name string, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer(grpc.UnaryInterceptor(h.calls.intercept))
+	register(s)
+	h.servers = append(h.servers, s)
+	go s.Serve(lis)
+
+	b := backend.New(name, h.backend)
+	if h.backends == nil {
+		h.backends = make(map[string]*backend.Backend)
+	}
+	h.backends[name] = b
+
+	conn, err := api.Dial(b, h.forward, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("e2e: dial: %v", err)
+	}
+	h.conns = append(h.conns, conn)
+
+	return conn
+}
+
+func (h *Harness) close() {
+	for _, c := range h.conns {
+		c.Close()
+	}
+	for _, s := range h.servers {
+		s.Stop()
+	}
+}
+
+// Backend returns the backend of the calls to the fake service called
+// name: citizens, palermo, plato or helenia.
+func (h *Harness) Backend(name string) *backend.Backend {
+	return h.backends[name]
+}
+
+// Session returns the credentials of a session of a user,
+// creating one if the user has none.
+func (h *Harness) Session(userID string) *mock.Session {
+	return h.Backends.Sessions.For(userID)
+}
+
+// Query sends a GraphQL document as the owner of sess.
+func (h *Harness) Query(t testing.TB, sess *mock.Session, query string) *Response {
+	t.Helper()
+
+	return h.Do(t, &Request{
+		Query:   query,
+		Session: sess,
+	})
+}
+
+// Do sends req through the gateway HTTP stack.
+func (h *Harness) Do(t testing.TB, req *Request) *Response {
+	t.Helper()
+
+	body, err := json.Marshal(&api.GraphRequest{Query: req.Query})
+	if err != nil {
+		t.Fatalf("e2e: marshal request: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	for key, values := range req.Header {
+		for _, v := range values {
+			r.Header.Add(key, v)
+		}
+	}
+	if r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", api.ContentTypeGraphQL)
+	}
+	if req.Session != nil {
+		r.Header.Set("Authorization", "Bearer "+req.Session.AuthToken)
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: req.Session.ValidationToken})
+	}
+
+	w := httptest.NewRecorder()
+	h.Handler.ServeHTTP(w, r)
+
+	res := &Response{
+		Status: w.Code,
+		Header: w.Header(),
+		Body:   w.Body.Bytes(),
+	}
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(res.Body, res); err != nil {
+			t.Fatalf("e2e: decode response %s: %v", res.Body, err)
+		}
+	}
+
+	return res
+}
+
+// Fail makes every call to the gRPC methods ending with method return err,
+// a nil err removes the failure. When several failures match a call, the one
+// added last is returned.
+func (h *Harness) Fail(method string, err error) {
+	h.calls.fail(method, err)
+}
+
+// Calls returns the calls received by the fake services for the gRPC
+// methods ending with method, e.g. "Talking/Get".
+func (h *Harness) Calls(method string) []Call {
+	return h.calls.find(method)
+}
+
+// ResetCalls forgets the calls recorded so far.
+func (h *Harness) ResetCalls() {
+	h.calls.reset()
+}
+
+// AssertCalled fails the test if the methods ending with method were not
+// called exactly n times.
+func (h *Harness) AssertCalled(t testing.TB, method string, n int) {
+	t.Helper()
+
+	if calls := h.Calls(method); len(calls) != n {
+		t.Fatalf("%s called %d times, want %d: %v", method, len(calls), n, calls)
+	}
+}
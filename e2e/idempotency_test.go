@@ -0,0 +1,138 @@
+package e2e_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-toschool/sicily/cmd/server/api"
+	"github.com/go-toschool/sicily/e2e"
+	"github.com/go-toschool/sicily/mock"
+	"github.com/graphql-go/graphql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const createTalk = `mutation {
+	createTalk(input: {title: "Go tooling", date: "2019-05-01T18:00:00Z"}) { id }
+}`
+
+func idempotent(h *e2e.Harness, t *testing.T, sess *mock.Session, query, key string) *e2e.Response {
+	t.Helper()
+
+	return h.Do(t, &e2e.Request{
+		Query:   query,
+		Session: sess,
+		Header:  http.Header{api.IdempotencyKeyHeader: {key}},
+	})
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	ada := h.Session("user-1")
+
+	first := idempotent(h, t, ada, createTalk, "key-1")
+	first.AssertNoErrors(t)
+	if got := first.Header.Get(api.IdempotencyReplayedHeader); got != "" {
+		t.Fatalf("%s = %q on the first request", api.IdempotencyReplayedHeader, got)
+	}
+	id, _ := first.Get("createTalk.id")
+
+	second := idempotent(h, t, ada, createTalk, "key-1")
+	second.AssertNoErrors(t)
+	if got := second.Header.Get(api.IdempotencyReplayedHeader); got != "true" {
+		t.Fatalf("%s = %q, want true", api.IdempotencyReplayedHeader, got)
+	}
+	second.AssertData(t, "createTalk.id", id)
+	h.AssertCalled(t, "Talking/Create", 1)
+
+	// The same key with another document is refused.
+	res := idempotent(h, t, ada, `mutation {
+		createTalk(input: {title: "Other", date: "2019-05-01T18:00:00Z"}) { id }
+	}`, "key-1")
+	res.AssertStatus(t, http.StatusUnprocessableEntity)
+	h.AssertCalled(t, "Talking/Create", 1)
+}
+
+func TestIdempotencyKeysPerOwner(t *testing.T) {
+	h := e2e.New(t, fixtures())
+
+	ada := idempotent(h, t, h.Session("user-1"), createTalk, "key-1")
+	ada.AssertNoErrors(t)
+	linus := idempotent(h, t, h.Session("user-3"), createTalk, "key-1")
+	linus.AssertNoErrors(t)
+
+	if got := linus.Header.Get(api.IdempotencyReplayedHeader); got != "" {
+		t.Fatalf("result of Ada replayed to Linus: %s", linus.Body)
+	}
+	adaID, _ := ada.Get("createTalk.id")
+	linusID, _ := linus.Get("createTalk.id")
+	if adaID == linusID {
+		t.Fatalf("both users got talk %v", adaID)
+	}
+	h.AssertCalled(t, "Talking/Create", 2)
+}
+
+func TestIdempotencyReleasedOnFailure(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	ada := h.Session("user-1")
+
+	h.Fail("Talking/Create", status.Error(codes.InvalidArgument, "invalid talk"))
+	res := idempotent(h, t, ada, createTalk, "key-1")
+	assertCode(t, res, "INVALID_ARGUMENT")
+
+	// The mutation did not run, so the key can be retried.
+	h.Fail("Talking/Create", nil)
+	res = idempotent(h, t, ada, createTalk, "key-1")
+	res.AssertNoErrors(t)
+	if got := res.Header.Get(api.IdempotencyReplayedHeader); got != "" {
+		t.Fatalf("failed result replayed: %s", res.Body)
+	}
+	h.AssertCalled(t, "Talking/Create", 2)
+}
+
+// pausedStore holds the result of the first mutation until resumed.
+type pausedStore struct {
+	*api.MemoryIdempotencyStore
+	paused chan struct{}
+	resume chan struct{}
+}
+
+func (s *pausedStore) Complete(key string, result *graphql.Result) {
+	select {
+	case <-s.paused:
+	default:
+		close(s.paused)
+		<-s.resume
+	}
+	s.MemoryIdempotencyStore.Complete(key, result)
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store := &pausedStore{
+		MemoryIdempotencyStore: api.NewMemoryIdempotencyStore(time.Hour),
+		paused:                 make(chan struct{}),
+		resume:                 make(chan struct{}),
+	}
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{Idempotency: store})
+	ada := h.Session("user-1")
+
+	done := make(chan *e2e.Response)
+	go func() {
+		done <- idempotent(h, t, ada, createTalk, "key-1")
+	}()
+	<-store.paused
+
+	res := idempotent(h, t, ada, createTalk, "key-1")
+	res.AssertStatus(t, http.StatusConflict)
+
+	close(store.resume)
+	(<-done).AssertNoErrors(t)
+
+	res = idempotent(h, t, ada, createTalk, "key-1")
+	res.AssertNoErrors(t)
+	if got := res.Header.Get(api.IdempotencyReplayedHeader); got != "true" {
+		t.Fatalf("%s = %q, want true", api.IdempotencyReplayedHeader, got)
+	}
+	h.AssertCalled(t, "Talking/Create", 1)
+}
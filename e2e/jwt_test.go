@@ -0,0 +1,111 @@
+package e2e_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/e2e"
+)
+
+// jwtIssuer signs the JWTs accepted by the verifier of its harness.
+type jwtIssuer struct {
+	key ed25519.PrivateKey
+}
+
+func newJWTIssuer(t *testing.T) (*jwtIssuer, *firewall.JWTVerifier) {
+	t.Helper()
+
+	public, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "OKP",
+		"kid": "e2e",
+		"crv": "Ed25519",
+		"x":   base64.RawURLEncoding.EncodeToString(public),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := firewall.NewKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &jwtIssuer{key: key}, firewall.NewJWTVerifier(keys, firewall.DefaultJWTOptions)
+}
+
+// token returns a JWT of subject granting scopes.
+func (i *jwtIssuer) token(t *testing.T, subject string, scopes ...string) string {
+	t.Helper()
+
+	segment := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	signed := segment(map[string]string{"alg": "EdDSA", "kid": "e2e"}) + "." + segment(map[string]interface{}{
+		"sub":   subject,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"scope": strings.Join(scopes, " "),
+	})
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(i.key, []byte(signed)))
+}
+
+// forge replaces the signature of token.
+func forge(token string) string {
+	return token[:strings.LastIndex(token, ".")+1] + base64.RawURLEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+}
+
+func TestJWTScopes(t *testing.T) {
+	issuer, verifier := newJWTIssuer(t)
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{JWT: verifier})
+
+	query := `{ talks { id } }`
+	mutation := `mutation { cancelTalk(id: "` + gid("Talk", "talk-1") + `") { id } }`
+
+	tests := []struct {
+		name   string
+		token  string
+		query  string
+		status int
+	}{
+		{"query with read", issuer.token(t, "user-1", "read"), query, http.StatusOK},
+		{"query without scopes", issuer.token(t, "user-1"), query, http.StatusForbidden},
+		{"query with write only", issuer.token(t, "user-1", "write"), query, http.StatusForbidden},
+		{"mutation with read", issuer.token(t, "user-1", "read"), mutation, http.StatusForbidden},
+		{"mutation with write", issuer.token(t, "user-1", "write"), mutation, http.StatusOK},
+		{"forged signature", forge(issuer.token(t, "user-1", "read")), query, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := h.Do(t, &e2e.Request{
+				Query:  tt.query,
+				Header: http.Header{"Authorization": {"Bearer " + tt.token}},
+			})
+			res.AssertStatus(t, tt.status)
+			if tt.status == http.StatusOK {
+				res.AssertNoErrors(t)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package e2e_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/sicily/e2e"
+	"github.com/go-toschool/sicily/mock"
+)
+
+// concurrently sends query once per session at the same time.
+func concurrently(t *testing.T, h *e2e.Harness, query string, sessions ...*mock.Session) []*e2e.Response {
+	t.Helper()
+
+	responses := make([]*e2e.Response, len(sessions))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, sess := range sessions {
+		wg.Add(1)
+		go func(i int, sess *mock.Session) {
+			defer wg.Done()
+			<-start
+			responses[i] = h.Query(t, sess, query)
+		}(i, sess)
+	}
+	close(start)
+	wg.Wait()
+
+	return responses
+}
+
+func TestRegistrationRace(t *testing.T) {
+	h := e2e.New(t, fixtures())
+	linus := h.Session("user-3")
+
+	sessions := make([]*mock.Session, 10)
+	for i := range sessions {
+		sessions[i] = linus
+	}
+	responses := concurrently(t, h, `mutation {
+		registerTalk(talk_id: "`+gid("Talk", "talk-1")+`") { assistant { id registrationStatus } }
+	}`, sessions...)
+
+	// Every request gets the one registration created.
+	first, _ := responses[0].Get("registerTalk.assistant.id")
+	for _, res := range responses {
+		res.AssertNoErrors(t)
+		res.AssertData(t, "registerTalk.assistant.id", first)
+		res.AssertData(t, "registerTalk.assistant.registrationStatus", "CONFIRMED")
+	}
+
+	aa, err := h.Backends.Assistants.Select(context.Background(), &assistants.SelectRequest{TalkId: "talk-1", Assistant: "user-3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(aa.GetData()); n != 1 {
+		t.Fatalf("%d registrations of Linus to talk-1", n)
+	}
+}
+
+func TestRegistrationCapacity(t *testing.T) {
+	h := e2e.New(t, fixtures())
+
+	// talk-1 has one seat left, Ada and Linus race for it.
+	responses := concurrently(t, h, `mutation {
+		registerTalk(talk_id: "`+gid("Talk", "talk-1")+`") { assistant { registrationStatus } }
+	}`, h.Session("user-1"), h.Session("user-3"))
+
+	statuses := make(map[interface{}]int)
+	for _, res := range responses {
+		res.AssertNoErrors(t)
+		status, _ := res.Get("registerTalk.assistant.registrationStatus")
+		statuses[status]++
+	}
+	if statuses["CONFIRMED"] != 1 || statuses["WAITLISTED"] != 1 {
+		t.Fatalf("registration statuses = %v, want one CONFIRMED and one WAITLISTED", statuses)
+	}
+
+	res := h.Query(t, h.Session("user-2"), `{ talk(id: "`+gid("Talk", "talk-1")+`") { attendeeCount waitlist { id } } }`)
+	res.AssertNoErrors(t)
+	res.AssertData(t, "talk.attendeeCount", 2)
+
+	// The seats taken can not be given back by lowering the capacity.
+	res = h.Query(t, h.Session("user-1"), `mutation {
+		updateTalk(id: "`+gid("Talk", "talk-1")+`", patch: {capacity: 1}) { capacity }
+	}`)
+	assertCode(t, res, "FAILED_PRECONDITION")
+}
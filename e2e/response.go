@@ -0,0 +1,113 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Error is a GraphQL error as returned to clients.
+type Error struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+// Response is the result of a request made through the harness.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+
+	Data   map[string]interface{} `json:"data"`
+	Errors []Error                `json:"errors"`
+}
+
+// AssertStatus fails the test if the HTTP status code is not want.
+func (r *Response) AssertStatus(t testing.TB, want int) {
+	t.Helper()
+
+	if r.Status != want {
+		t.Fatalf("status = %d, want %d; body: %s", r.Status, want, r.Body)
+	}
+}
+
+// AssertNoErrors fails the test if the request failed or returned GraphQL
+// errors.
+func (r *Response) AssertNoErrors(t testing.TB) {
+	t.Helper()
+
+	r.AssertStatus(t, http.StatusOK)
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %s", r.Body)
+	}
+}
+
+// AssertError fails the test if no GraphQL error message contains message.
+func (r *Response) AssertError(t testing.TB, message string) {
+	t.Helper()
+
+	for _, e := range r.Errors {
+		if strings.Contains(e.Message, message) {
+			return
+		}
+	}
+	t.Fatalf("no error containing %q in: %s", message, r.Body)
+}
+
+// AssertData fails the test if the value at path is not want. The path is a
+// dot separated list of fields and list indexes, e.g. "user.talks.0.title",
+// and want is compared against the JSON representation of the response.
+func (r *Response) AssertData(t testing.TB, path string, want interface{}) {
+	t.Helper()
+
+	got, ok := r.Get(path)
+	if !ok {
+		t.Fatalf("no data at %q in: %s", path, r.Body)
+	}
+
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("marshal %v: %v", want, err)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		t.Fatalf("unmarshal %s: %v", b, err)
+	}
+
+	if !reflect.DeepEqual(got, normalized) {
+		t.Fatalf("data at %q = %#v, want %#v", path, got, normalized)
+	}
+}
+
+// Get returns the value at path, see AssertData for the path syntax.
+func (r *Response) Get(path string) (interface{}, bool) {
+	var current interface{} = r.Data
+	if path == "" {
+		return current, true
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			value, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			current = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
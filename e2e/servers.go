@@ -0,0 +1,98 @@
+package e2e
+
+import (
+	"context"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/palermo/auth"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily/mock"
+	"github.com/go-toschool/syracuse/citizens"
+)
+
+// The fake servers expose the in-memory mock backends as gRPC servers, so
+// requests cross a real gRPC connection before reaching them.
+
+type citizensServer struct{ backend *mock.Citizens }
+
+func (s *citizensServer) Get(ctx context.Context, in *citizens.GetRequest) (*citizens.GetResponse, error) {
+	return s.backend.Get(ctx, in)
+}
+
+func (s *citizensServer) Select(ctx context.Context, in *citizens.SelectRequest) (*citizens.SelectResponse, error) {
+	return s.backend.Select(ctx, in)
+}
+
+func (s *citizensServer) Create(ctx context.Context, in *citizens.CreateRequest) (*citizens.CreateResponse, error) {
+	return s.backend.Create(ctx, in)
+}
+
+func (s *citizensServer) Update(ctx context.Context, in *citizens.UpdateRequest) (*citizens.UpdateResponse, error) {
+	return s.backend.Update(ctx, in)
+}
+
+func (s *citizensServer) Delete(ctx context.Context, in *citizens.DeleteRequest) (*citizens.DeleteResponse, error) {
+	return s.backend.Delete(ctx, in)
+}
+
+type talksServer struct{ backend *mock.Talks }
+
+func (s *talksServer) Get(ctx context.Context, in *talks.GetRequest) (*talks.GetResponse, error) {
+	return s.backend.Get(ctx, in)
+}
+
+func (s *talksServer) Select(ctx context.Context, in *talks.SelectRequest) (*talks.SelectResponse, error) {
+	return s.backend.Select(ctx, in)
+}
+
+func (s *talksServer) Create(ctx context.Context, in *talks.CreateRequest) (*talks.CreateResponse, error) {
+	return s.backend.Create(ctx, in)
+}
+
+func (s *talksServer) Update(ctx context.Context, in *talks.UpdateRequest) (*talks.UpdateResponse, error) {
+	return s.backend.Update(ctx, in)
+}
+
+func (s *talksServer) Delete(ctx context.Context, in *talks.DeleteRequest) (*talks.DeleteResponse, error) {
+	return s.backend.Delete(ctx, in)
+}
+
+func (s *talksServer) Cancel(ctx context.Context, in *talks.CancelRequest) (*talks.CancelResponse, error) {
+	return s.backend.Cancel(ctx, in)
+}
+
+type assistantsServer struct{ backend *mock.Assistants }
+
+func (s *assistantsServer) Get(ctx context.Context, in *assistants.GetRequest) (*assistants.GetResponse, error) {
+	return s.backend.Get(ctx, in)
+}
+
+func (s *assistantsServer) Select(ctx context.Context, in *assistants.SelectRequest) (*assistants.SelectResponse, error) {
+	return s.backend.Select(ctx, in)
+}
+
+func (s *assistantsServer) Create(ctx context.Context, in *assistants.CreateRequest) (*assistants.CreateResponse, error) {
+	return s.backend.Create(ctx, in)
+}
+
+func (s *assistantsServer) Update(ctx context.Context, in *assistants.UpdateRequest) (*assistants.UpdateResponse, error) {
+	return s.backend.Update(ctx, in)
+}
+
+func (s *assistantsServer) Delete(ctx context.Context, in *assistants.DeleteRequest) (*assistants.DeleteResponse, error) {
+	return s.backend.Delete(ctx, in)
+}
+
+type authServer struct{ backend *mock.Sessions }
+
+func (s *authServer) Get(ctx context.Context, in *auth.GetRequest) (*auth.GetResponse, error) {
+	return s.backend.Get(ctx, in)
+}
+
+func (s *authServer) Create(ctx context.Context, in *auth.CreateRequest) (*auth.CreateResponse, error) {
+	return s.backend.Create(ctx, in)
+}
+
+func (s *authServer) Delete(ctx context.Context, in *auth.DeleteRequest) (*auth.DeleteResponse, error) {
+	return s.backend.Delete(ctx, in)
+}
@@ -0,0 +1,40 @@
+package e2e_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-toschool/sicily/cmd/server/firewall"
+	"github.com/go-toschool/sicily/e2e"
+	"github.com/go-toschool/sicily/mock"
+)
+
+func TestSessionCache(t *testing.T) {
+	h := e2e.NewWithOptions(t, fixtures(), e2e.Options{
+		Sessions: firewall.NewSessionCache(100, time.Minute, time.Minute),
+	})
+	ada := h.Session("user-1")
+	forged := &mock.Session{AuthToken: "forged", ValidationToken: "forged"}
+	query := `{ talks { id } }`
+
+	h.Query(t, ada, query).AssertNoErrors(t)
+	h.Query(t, ada, query).AssertNoErrors(t)
+	h.Query(t, forged, query).AssertStatus(t, http.StatusUnauthorized)
+	h.Query(t, forged, query).AssertStatus(t, http.StatusUnauthorized)
+	h.AssertCalled(t, "AuthService/Get", 2)
+
+	// Logging out drops the session from the cache.
+	r := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	r.Header.Set("Authorization", "Bearer "+ada.AuthToken)
+	r.AddCookie(&http.Cookie{Name: "access_token", Value: ada.ValidationToken})
+	w := httptest.NewRecorder()
+	h.Handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("logout status = %d: %s", w.Code, w.Body)
+	}
+
+	h.Query(t, ada, query).AssertStatus(t, http.StatusUnauthorized)
+	h.AssertCalled(t, "AuthService/Get", 3)
+}
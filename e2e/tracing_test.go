@@ -0,0 +1,92 @@
+package e2e_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-toschool/sicily/e2e"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// The tracers of the gateway are bound to the first global provider set, so
+// it is set once and the tests record its spans.
+var (
+	providerOnce sync.Once
+	provider     *sdktrace.TracerProvider
+)
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	providerOnce.Do(func() {
+		provider = sdktrace.NewTracerProvider()
+		otel.SetTracerProvider(provider)
+	})
+
+	rec := tracetest.NewSpanRecorder()
+	provider.RegisterSpanProcessor(rec)
+	t.Cleanup(func() { provider.UnregisterSpanProcessor(rec) })
+	return rec
+}
+
+func TestTracing(t *testing.T) {
+	rec := record(t)
+
+	h := e2e.New(t, fixtures())
+	query := `query Speakers { talks { speaker { full_name } attendees { id } } }`
+	h.Query(t, h.Session("user-1"), query).AssertNoErrors(t)
+
+	if n := len(rec.Started()); n != len(rec.Ended()) {
+		t.Fatalf("%d spans started, %d ended", n, len(rec.Ended()))
+	}
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	byID := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range rec.Ended() {
+		spans[s.Name()] = append(spans[s.Name()], s)
+		byID[s.SpanContext().SpanID().String()] = s
+	}
+
+	// The calls of the loaders are children of the field that loaded them,
+	// which ends once they returned.
+	for _, field := range []struct{ name, call string }{
+		{"Talk.speaker", "Citizenship/Get"},
+		{"Talk.attendees", "Assistants/Select"},
+	} {
+		var calls int
+		for name, ss := range spans {
+			if !strings.HasSuffix(name, field.call) {
+				continue
+			}
+			for _, call := range ss {
+				calls++
+				parent, ok := byID[call.Parent().SpanID().String()]
+				if !ok || parent.Name() != field.name {
+					t.Fatalf("%s is not a child of %s", name, field.name)
+				}
+				if parent.EndTime().Before(call.EndTime()) {
+					t.Fatalf("%s ended before its call %s", field.name, name)
+				}
+			}
+		}
+		if calls == 0 {
+			t.Fatalf("no %s span", field.call)
+		}
+	}
+
+	execute := spans["graphql.execute"]
+	if len(execute) != 1 {
+		t.Fatalf("%d graphql.execute spans", len(execute))
+	}
+	attrs := make(map[string]string)
+	for _, kv := range execute[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if _, ok := attrs["graphql.document"]; ok {
+		t.Fatal("the document is recorded on the span")
+	}
+	if attrs["graphql.operation"] != "query Speakers" || len(attrs["graphql.document.sha256"]) != 64 {
+		t.Fatalf("graphql.execute attributes = %v", attrs)
+	}
+}
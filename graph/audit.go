@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-toschool/palermo/auth"
+)
+
+// Outcomes of an audited mutation.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry records a mutation executed through the gateway.
+type AuditEntry struct {
+	Time      time.Time              `json:"time"`
+	UserID    string                 `json:"user_id,omitempty"`
+	Principal string                 `json:"principal,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Operation string                 `json:"operation,omitempty"`
+	Mutation  string                 `json:"mutation"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	TargetIDs []string               `json:"target_ids,omitempty"`
+	Outcome   string                 `json:"outcome"`
+	ErrorCode string                 `json:"error_code,omitempty"`
+}
+
+// AuditSink stores the audit trail, entries must never be rewritten.
+type AuditSink interface {
+	Write(ctx context.Context, e *AuditEntry) error
+}
+
+// AuditReader is implemented by the sinks able to read back their most
+// recent entries, newest first.
+type AuditReader interface {
+	Recent(ctx context.Context, limit int) ([]*AuditEntry, error)
+}
+
+// Caller describes who runs a request, as known by the HTTP layer.
+type Caller struct {
+	UserID string
+	// Principal is the service authenticated by an API key, if any.
+	Principal string
+	// Session is the palermo session of a user authenticated by one, if
+	// any.
+	Session   *auth.Session
+	RequestID string
+	Admin     bool
+}
+
+type callerKey struct{}
+
+// WithCaller returns a copy of parent carrying c.
+func WithCaller(parent context.Context, c *Caller) context.Context {
+	return context.WithValue(parent, callerKey{}, c)
+}
+
+// CallerFromContext returns the Caller stored in ctx by WithCaller.
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	c, ok := ctx.Value(callerKey{}).(*Caller)
+	return c, ok
+}
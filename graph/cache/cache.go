@@ -0,0 +1,150 @@
+// Package cache computes how long the response to a query can be cached
+// from the hints declared on the fields and types of the schema.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Scope tells who a cached response can be served to.
+type Scope int
+
+const (
+	// Public responses are the same for every user.
+	Public Scope = iota
+	// Private responses depend on the user sending the query.
+	Private
+)
+
+// Hint declares how long the value of a field can be cached.
+type Hint struct {
+	MaxAge time.Duration
+	Scope  Scope
+}
+
+// CacheControl returns the Cache-Control header value for h.
+func (h Hint) CacheControl() string {
+	if h.MaxAge < time.Second {
+		return "no-store"
+	}
+
+	scope := "public"
+	if h.Scope == Private {
+		scope = "private"
+	}
+	return fmt.Sprintf("%s, max-age=%d", scope, int(h.MaxAge/time.Second))
+}
+
+// Config declares the cache hints of a schema.
+type Config struct {
+	// Hints are keyed by type and field name ("Talk.viewerRegistration"), or
+	// by type name to apply to every field returning the type and to every
+	// field of the type. Fields returning an object without a hint are not
+	// cached, fields returning a scalar without a hint do not change the
+	// policy of their object.
+	Hints map[string]Hint
+	// Invalidates lists by mutation name the types whose cached responses
+	// the mutation makes stale.
+	Invalidates map[string][]string
+}
+
+// Policy accumulates the hints of the fields resolved by one request.
+type Policy struct {
+	mu          sync.Mutex
+	hint        Hint
+	restricted  bool
+	types       map[string]bool
+	invalidated map[string]bool
+}
+
+// NewPolicy returns a policy with no hints.
+func NewPolicy() *Policy {
+	return &Policy{
+		types:       make(map[string]bool),
+		invalidated: make(map[string]bool),
+	}
+}
+
+// Hint returns the shortest max age and the most restrictive scope of the
+// hints applied, a zero Hint when there were none.
+func (p *Policy) Hint() Hint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.restricted {
+		return Hint{}
+	}
+	return p.hint
+}
+
+// Types returns the names of the types resolved.
+func (p *Policy) Types() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return keys(p.types)
+}
+
+// Invalidated returns the names of the types made stale by the mutations
+// executed.
+func (p *Policy) Invalidated() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return keys(p.invalidated)
+}
+
+func (p *Policy) restrict(h Hint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.restricted || h.MaxAge < p.hint.MaxAge {
+		p.hint.MaxAge = h.MaxAge
+	}
+	if h.Scope == Private {
+		p.hint.Scope = Private
+	}
+	p.restricted = true
+}
+
+func (p *Policy) touch(typeName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.types[typeName] = true
+}
+
+func (p *Policy) invalidate(types []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range types {
+		p.invalidated[t] = true
+	}
+}
+
+func keys(m map[string]bool) []string {
+	kk := make([]string, 0, len(m))
+	for k := range m {
+		kk = append(kk, k)
+	}
+	sort.Strings(kk)
+	return kk
+}
+
+type policyKey struct{}
+
+// WithPolicy returns a copy of parent carrying p, the Extension records the
+// hints of the fields resolved with that context into p.
+func WithPolicy(parent context.Context, p *Policy) context.Context {
+	return context.WithValue(parent, policyKey{}, p)
+}
+
+func policyFromContext(ctx context.Context) (*Policy, bool) {
+	p, ok := ctx.Value(policyKey{}).(*Policy)
+	return p, ok
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Extension applies the hints of config to the Policy found in the context
+// of the requests executed against the schema.
+type Extension struct {
+	config Config
+}
+
+// NewExtension returns an extension applying the hints of config.
+func NewExtension(config Config) *Extension {
+	return &Extension{config}
+}
+
+func (e *Extension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (e *Extension) Name() string {
+	return "cacheControl"
+}
+
+func (e *Extension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (e *Extension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (e *Extension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (e *Extension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	if p, ok := policyFromContext(ctx); ok {
+		e.apply(p, info)
+	}
+	return ctx, func(interface{}, error) {}
+}
+
+func (e *Extension) HasResult() bool {
+	return false
+}
+
+func (e *Extension) GetResult(context.Context) interface{} {
+	return nil
+}
+
+func (e *Extension) apply(p *Policy, info *graphql.ResolveInfo) {
+	parent := info.ParentType.Name()
+	p.touch(parent)
+
+	if op, ok := info.Operation.(*ast.OperationDefinition); ok && op.Operation == ast.OperationTypeMutation {
+		if info.Path != nil && info.Path.Prev == nil {
+			p.invalidate(e.config.Invalidates[info.FieldName])
+		}
+	}
+
+	var named string
+	switch t := graphql.GetNamed(info.ReturnType).(type) {
+	case *graphql.Object, *graphql.Interface, *graphql.Union:
+		named = t.String()
+		p.touch(named)
+	}
+
+	// The hint of an object also restricts its fields, objects resolved
+	// through an interface, like node, never applied it otherwise.
+	if h, ok := e.config.Hints[parent]; ok {
+		p.restrict(h)
+	}
+
+	if h, ok := e.config.Hints[parent+"."+info.FieldName]; ok {
+		p.restrict(h)
+		return
+	}
+	if named == "" {
+		return
+	}
+	if h, ok := e.config.Hints[named]; ok {
+		p.restrict(h)
+		return
+	}
+	p.restrict(Hint{})
+}
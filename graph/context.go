@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"context"
+
 	"github.com/go-toschool/helenia/assistants"
 	"github.com/go-toschool/palermo/auth"
 	"github.com/go-toschool/platon/talks"
@@ -13,4 +15,21 @@ type Context struct {
 	AssistantsService assistants.AssistantsClient
 
 	SessionService auth.AuthServiceClient
+
+	// Audit records every mutation, nil disables the audit trail.
+	Audit AuditSink
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of parent carrying c, it lets resolvers declared
+// in graph/types reach the services.
+func NewContext(parent context.Context, c *Context) context.Context {
+	return context.WithValue(parent, contextKey{}, c)
+}
+
+// FromContext returns the Context stored in ctx by NewContext.
+func FromContext(ctx context.Context) (*Context, bool) {
+	c, ok := ctx.Value(contextKey{}).(*Context)
+	return c, ok
 }
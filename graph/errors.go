@@ -0,0 +1,28 @@
+package graph
+
+import (
+	"strings"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorCode returns the code reported in the extensions of a GraphQL error
+// caused by err: the gRPC code of err in upper snake case ("NOT_FOUND"), or
+// INTERNAL for errors without one.
+func ErrorCode(err error) string {
+	code := status.Code(err)
+	if code == codes.Unknown {
+		return "INTERNAL"
+	}
+
+	var b strings.Builder
+	for i, r := range code.String() {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,215 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/syracuse/citizens"
+)
+
+// UserLoader deduplicates the users requested while executing one query:
+// ids queued with Load are fetched the first time one of the returned thunks
+// is called, with one concurrent citizens Get per distinct id, as citizens
+// can not select users by ids. graphql-go calls thunks after resolving every
+// field of a level, so listing many talks loads their speakers in a single
+// round of parallel calls, each speaker once, instead of one call per talk
+// made in turn.
+type UserLoader struct {
+	service citizens.CitizenshipClient
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]*userResult
+}
+
+type userResult struct {
+	// ctx is the context of the first field loading the user, its call
+	// is a child of that field.
+	ctx  context.Context
+	user *citizens.Citizen
+	err  error
+	done chan struct{}
+}
+
+// NewUserLoader returns an empty loader fetching users from service.
+func NewUserLoader(service citizens.CitizenshipClient) *UserLoader {
+	return &UserLoader{
+		service: service,
+		results: make(map[string]*userResult),
+	}
+}
+
+// Load queues id for the field resolved with ctx and returns a thunk that
+// returns its user, nil when the user does not exist.
+func (l *UserLoader) Load(ctx context.Context, id string) func() (interface{}, error) {
+	l.mu.Lock()
+	r, ok := l.results[id]
+	if !ok {
+		r = &userResult{ctx: ctx, done: make(chan struct{})}
+		l.results[id] = r
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.flush()
+		<-r.done
+		if r.err != nil {
+			return nil, r.err
+		}
+		if r.user == nil {
+			return nil, nil
+		}
+		return r.user, nil
+	}
+}
+
+// flush gets the queued ids concurrently, one call per id.
+func (l *UserLoader) flush() {
+	l.mu.Lock()
+	ids := l.pending
+	l.pending = nil
+	results := make([]*userResult, len(ids))
+	for i, id := range ids {
+		results[i] = l.results[id]
+	}
+	l.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(r *userResult, id string) {
+			defer wg.Done()
+			defer close(r.done)
+
+			u, err := l.service.Get(r.ctx, &citizens.GetRequest{
+				UserId: id,
+			})
+			if err != nil {
+				r.err = err
+				return
+			}
+			r.user = u.GetData()
+		}(results[i], id)
+	}
+	wg.Wait()
+}
+
+type userLoaderKey struct{}
+
+// WithUserLoader returns a copy of parent carrying l.
+func WithUserLoader(parent context.Context, l *UserLoader) context.Context {
+	return context.WithValue(parent, userLoaderKey{}, l)
+}
+
+// UserLoaderFromContext returns the loader stored in ctx by WithUserLoader.
+func UserLoaderFromContext(ctx context.Context) (*UserLoader, bool) {
+	l, ok := ctx.Value(userLoaderKey{}).(*UserLoader)
+	return l, ok
+}
+
+// AssistantsLoader loads the assistants of the talks resolved while
+// executing one query: each talk is selected from helenia once, however many
+// of attendees, attendeeCount, waitlist and viewerRegistration are requested,
+// and the talks queued at one level are selected concurrently.
+type AssistantsLoader struct {
+	service assistants.AssistantsClient
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]*assistantsResult
+}
+
+type assistantsResult struct {
+	// ctx is the context of the first field loading the talk.
+	ctx        context.Context
+	assistants []*assistants.Assistant
+	err        error
+	done       chan struct{}
+}
+
+// NewAssistantsLoader returns an empty loader selecting assistants from
+// service.
+func NewAssistantsLoader(service assistants.AssistantsClient) *AssistantsLoader {
+	return &AssistantsLoader{
+		service: service,
+		results: make(map[string]*assistantsResult),
+	}
+}
+
+// Load queues talkID for the field resolved with ctx and returns a function
+// that returns the assistants of the talk in registration order.
+func (l *AssistantsLoader) Load(ctx context.Context, talkID string) func() ([]*assistants.Assistant, error) {
+	l.mu.Lock()
+	r, ok := l.results[talkID]
+	if !ok {
+		r = &assistantsResult{ctx: ctx, done: make(chan struct{})}
+		l.results[talkID] = r
+		l.pending = append(l.pending, talkID)
+	}
+	l.mu.Unlock()
+
+	return func() ([]*assistants.Assistant, error) {
+		l.flush()
+		<-r.done
+		return r.assistants, r.err
+	}
+}
+
+// Clear forgets the assistants loaded for talkID. Mutations call it before
+// changing the registrations of a talk: graphql-go calls the thunks of a
+// mutation after running the next ones, so the loads already queued are
+// settled first and see the talk as it was.
+func (l *AssistantsLoader) Clear(talkID string) {
+	l.flush()
+
+	l.mu.Lock()
+	delete(l.results, talkID)
+	l.mu.Unlock()
+}
+
+// flush selects the queued talks concurrently.
+func (l *AssistantsLoader) flush() {
+	l.mu.Lock()
+	ids := l.pending
+	l.pending = nil
+	results := make([]*assistantsResult, len(ids))
+	for i, id := range ids {
+		results[i] = l.results[id]
+	}
+	l.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(r *assistantsResult, id string) {
+			defer wg.Done()
+			defer close(r.done)
+
+			aa, err := l.service.Select(r.ctx, &assistants.SelectRequest{
+				TalkId: id,
+			})
+			if err != nil {
+				r.err = err
+				return
+			}
+			r.assistants = aa.GetData()
+		}(results[i], id)
+	}
+	wg.Wait()
+}
+
+type assistantsLoaderKey struct{}
+
+// WithAssistantsLoader returns a copy of parent carrying l.
+func WithAssistantsLoader(parent context.Context, l *AssistantsLoader) context.Context {
+	return context.WithValue(parent, assistantsLoaderKey{}, l)
+}
+
+// AssistantsLoaderFromContext returns the loader stored in ctx by
+// WithAssistantsLoader.
+func AssistantsLoaderFromContext(ctx context.Context) (*AssistantsLoader, bool) {
+	l, ok := ctx.Value(assistantsLoaderKey{}).(*AssistantsLoader)
+	return l, ok
+}
@@ -0,0 +1,161 @@
+package mutation
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/sicily/graph/types"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// maxAuditedString bounds the length of the string arguments kept in the
+	// audit trail.
+	maxAuditedString = 256
+)
+
+// sensitiveArgs are the words marking an argument as secret.
+var sensitiveArgs = []string{"token", "password", "secret"}
+
+// audited wraps the resolver of the mutation name so every execution is
+// written to the audit sink of ctx. The entry is written once the mutation
+// ran, so when the sink fails the mutation is not undone nor reported as
+// failed: the entry is lost, which is logged and counted by the sink.
+func audited(ctx *graph.Context, name string, f *graphql.Field) *graphql.Field {
+	resolve := f.Resolve
+	f.Resolve = func(params graphql.ResolveParams) (interface{}, error) {
+		res, err := resolve(params)
+
+		e := &graph.AuditEntry{
+			Time:      time.Now().UTC(),
+			Operation: operationName(params.Info),
+			Mutation:  name,
+			Arguments: sanitize(params.Args),
+			TargetIDs: targetIDs(params, res, err),
+			Outcome:   graph.AuditSuccess,
+		}
+		if c, ok := graph.CallerFromContext(params.Context); ok {
+			e.UserID = c.UserID
+			e.Principal = c.Principal
+			e.RequestID = c.RequestID
+		}
+		if err != nil {
+			e.Outcome = graph.AuditFailure
+			e.ErrorCode = graph.ErrorCode(err)
+		}
+
+		if werr := ctx.Audit.Write(params.Context, e); werr != nil {
+			slog.ErrorContext(params.Context, "audit: write failed, entry lost", "mutation", name, "request_id", e.RequestID, "error", werr)
+		}
+
+		return res, err
+	}
+
+	return f
+}
+
+func operationName(info graphql.ResolveInfo) string {
+	op, ok := info.Operation.(*ast.OperationDefinition)
+	if !ok || op.Name == nil {
+		return ""
+	}
+	return op.Name.Value
+}
+
+// sanitize copies args redacting the secrets and truncating long strings.
+func sanitize(args map[string]interface{}) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if sensitive(k) {
+			out[k] = redacted
+			continue
+		}
+		out[k] = sanitizeValue(v)
+	}
+	return out
+}
+
+func sanitizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return sanitize(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = sanitizeValue(item)
+		}
+		return list
+	case string:
+		if r := []rune(v); len(r) > maxAuditedString {
+			return string(r[:maxAuditedString]) + "…"
+		}
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return v
+	}
+}
+
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveArgs {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetIDs lists the global ids of the objects given as arguments and of
+// the object the mutation returned.
+func targetIDs(params graphql.ResolveParams, res interface{}, err error) []string {
+	returned := graphql.GetNamed(params.Info.ReturnType).String()
+
+	seen := make(map[string]bool)
+	collectIDs(params.Args, returned, seen)
+
+	if n, ok := res.(interface{ GetId() string }); ok && err == nil && n.GetId() != "" {
+		seen[types.GlobalID(returned, n.GetId())] = true
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// collectIDs adds the id arguments to seen, the type of "id" is the type
+// returned by the mutation and the type of "talk_id" is Talk.
+func collectIDs(args map[string]interface{}, returned string, seen map[string]bool) {
+	for k, v := range args {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			collectIDs(v, returned, seen)
+		case string:
+			if v == "" {
+				continue
+			}
+			if k == "id" {
+				seen[types.GlobalID(returned, types.LocalID(returned, v))] = true
+			} else if prefix := strings.TrimSuffix(k, "_id"); prefix != k {
+				typeName := strings.ToUpper(prefix[:1]) + prefix[1:]
+				seen[types.GlobalID(typeName, types.LocalID(typeName, v))] = true
+			}
+		}
+	}
+}
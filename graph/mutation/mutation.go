@@ -1,17 +1,49 @@
 package mutation
 
 import (
+	"errors"
+
+	"github.com/go-toschool/sicily"
 	"github.com/go-toschool/sicily/graph"
 	"github.com/graphql-go/graphql"
 )
 
 func Mutations(ctx *graph.Context) *graphql.Object {
+	fields := graphql.Fields{
+		"createTalk":     CreateTalk(ctx),
+		"updateTalk":     UpdateTalk(ctx),
+		"deleteTalk":     DeleteTalk(ctx),
+		"cancelTalk":     CancelTalk(ctx),
+		"registerTalk":   RegisterTalk(ctx),
+		"unregisterTalk": UnregisterTalk(ctx),
+		"updateUser":     UpdateUser(ctx),
+	}
+
+	if ctx.Audit != nil {
+		for name, f := range fields {
+			fields[name] = audited(ctx, name, f)
+		}
+	}
+
 	return graphql.NewObject(graphql.ObjectConfig{
-		Name: "Mutations",
-		Fields: graphql.Fields{
-			"createTalk":   CreateTalk(ctx),
-			"registerTalk": RegisterTalk(ctx),
-			"updateUser":   UpdateUser(ctx),
-		},
+		Name:   "Mutations",
+		Fields: fields,
 	})
 }
+
+// currentUser returns the id of the authenticated user running the mutation.
+func currentUser(params graphql.ResolveParams) (string, error) {
+	userID, ok := params.Context.Value(sicily.UserIDKey).(string)
+	if !ok || userID == "" {
+		return "", errors.New("Missing authenticated user")
+	}
+
+	return userID, nil
+}
+
+// onBehalf reports whether the caller may act for another user: admins and
+// services authenticated by an API key.
+func onBehalf(params graphql.ResolveParams) bool {
+	c, ok := graph.CallerFromContext(params.Context)
+	return ok && (c.Admin || c.Principal != "")
+}
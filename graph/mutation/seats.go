@@ -0,0 +1,119 @@
+package mutation
+
+import (
+	"context"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/sicily/graph/types"
+	"github.com/graphql-go/graphql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Seats are counted and taken by helenia: registrations and promotions send
+// the capacity of the talk, helenia waitlists a new assistant when the talk
+// is full and refuses to confirm one with FailedPrecondition. It also refuses
+// a second assistant of a user to a talk with AlreadyExists. Checking either
+// in the gateway would race with the requests served concurrently, by this
+// instance or others.
+
+// registration returns the assistant of userID to talkID, nil when the user
+// is not registered.
+func registration(ctxb context.Context, ctx *graph.Context, talkID, userID string) (*assistants.Assistant, error) {
+	aa, err := ctx.AssistantsService.Select(ctxb, &assistants.SelectRequest{
+		TalkId:    talkID,
+		Assistant: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range aa.GetData() {
+		if a.GetAssistant() == userID {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+// promoteWaitlist gives the free seats of a talk to the waitlist in
+// registration order.
+func promoteWaitlist(ctxb context.Context, ctx *graph.Context, t *talks.Talk) error {
+	aa, err := ctx.AssistantsService.Select(ctxb, &assistants.SelectRequest{
+		TalkId: t.GetId(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, waitlist := types.SplitAttendees(aa.GetData())
+	for _, a := range waitlist {
+		_, err := ctx.AssistantsService.Update(ctxb, &assistants.UpdateRequest{
+			Id: a.GetId(),
+			Data: &assistants.Assistant{
+				Status: types.StatusConfirmed,
+			},
+			Capacity: t.GetCapacity(),
+		})
+		if status.Code(err) == codes.FailedPrecondition {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkCapacity fails when capacity leaves fewer seats than the assistants
+// of the talk already hold, confirmed assistants are never moved back to the
+// waitlist.
+func checkCapacity(ctxb context.Context, ctx *graph.Context, talkID string, capacity int64) error {
+	aa, err := ctx.AssistantsService.Select(ctxb, &assistants.SelectRequest{
+		TalkId: talkID,
+	})
+	if err != nil {
+		return err
+	}
+
+	confirmed, _ := types.SplitAttendees(aa.GetData())
+	if int64(len(confirmed)) > capacity {
+		return status.Error(codes.FailedPrecondition, "Capacity is below the number of attendees")
+	}
+
+	return nil
+}
+
+// clearAssistants must be called before changing the assistants of talkID,
+// so the fields selected on the result of the mutation load them again.
+func clearAssistants(params graphql.ResolveParams, talkID string) {
+	if l, ok := graph.AssistantsLoaderFromContext(params.Context); ok {
+		l.Clear(talkID)
+	}
+}
+
+// deleteAssistants removes the registrations of a deleted talk. It runs once
+// the talk is gone so no registration is created meanwhile.
+func deleteAssistants(ctxb context.Context, ctx *graph.Context, params graphql.ResolveParams, talkID string) error {
+	aa, err := ctx.AssistantsService.Select(ctxb, &assistants.SelectRequest{
+		TalkId: talkID,
+	})
+	if err != nil {
+		return err
+	}
+
+	clearAssistants(params, talkID)
+	for _, a := range aa.GetData() {
+		_, err := ctx.AssistantsService.Delete(ctxb, &assistants.DeleteRequest{
+			Id: a.GetId(),
+		})
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	return nil
+}
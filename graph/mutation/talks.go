@@ -3,6 +3,7 @@ package mutation
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/go-toschool/platon/talks"
@@ -11,73 +12,60 @@ import (
 	"github.com/go-toschool/sicily/graph"
 	"github.com/go-toschool/sicily/graph/types"
 	"github.com/graphql-go/graphql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 // CreateTalk create a talk in remote service.
 func CreateTalk(ctx *graph.Context) *graphql.Field {
 	return &graphql.Field{
-		Type:        types.Talk,
-		Description: "Create talk",
+		Type:        graphql.NewNonNull(types.Talk),
+		Description: "Create a talk given by the authenticated user",
 		Args: graphql.FieldConfigArgument{
-			"title": &graphql.ArgumentConfig{
-				Type: graphql.String,
-			},
-			"description": &graphql.ArgumentConfig{
-				Type: graphql.String,
-			},
-			"repository": &graphql.ArgumentConfig{
-				Type: graphql.String,
-			},
-			"date": &graphql.ArgumentConfig{
-				Type: graphql.DateTime,
-			},
-			"tags": &graphql.ArgumentConfig{
-				Type: graphql.String,
-			},
-			"user_id": &graphql.ArgumentConfig{
-				Type: graphql.String,
+			"input": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(types.CreateTalkInput),
 			},
 		},
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-			title, ok := params.Args["title"].(string)
-			if !ok {
-				return nil, errors.New("Invalid title")
-			}
-
-			description, ok := params.Args["description"].(string)
+			input, ok := params.Args["input"].(map[string]interface{})
 			if !ok {
-				return nil, errors.New("Invalid description")
+				return nil, errors.New("Invalid input")
 			}
 
-			repository, ok := params.Args["repository"].(string)
+			title, ok := input["title"].(string)
 			if !ok {
-				return nil, errors.New("Invalid repository")
+				return nil, errors.New("Invalid title")
 			}
 
-			date, ok := params.Args["date"].(time.Time)
+			date, ok := input["date"].(time.Time)
 			if !ok {
 				return nil, errors.New("Invalid date")
 			}
 
-			tags, ok := params.Args["tags"].(string)
-			if !ok {
-				return nil, errors.New("Invalid tags")
+			description, _ := input["description"].(string)
+			repository, _ := input["repository"].(string)
+
+			capacity, _ := input["capacity"].(int)
+			if capacity < 0 {
+				return nil, errors.New("Invalid capacity")
 			}
 
-			userID, ok := params.Args["user_id"].(string)
-			if !ok {
-				return nil, errors.New("Invalid user_id")
+			userID, err := currentUser(params)
+			if err != nil {
+				return nil, err
 			}
 
-			ctxb := context.Background()
+			ctxb := params.Context
 			opts := &talks.CreateRequest{
 				Talk: &talks.Talk{
 					Title:       title,
 					Description: description,
 					Repository:  repository,
 					Date:        date.Unix(),
-					Tags:        tags,
+					Tags:        types.JoinTags(input["tags"]),
 					UserId:      userID,
+					Capacity:    int64(capacity),
 				},
 			}
 
@@ -91,17 +79,226 @@ func CreateTalk(ctx *graph.Context) *graphql.Field {
 	}
 }
 
-// RegisterTalk register a user into talk.
+// UpdateTalk changes the given fields of a talk, only its speaker can update it
+// and only while it is not cancelled.
+func UpdateTalk(ctx *graph.Context) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(types.Talk),
+		Description: "Update talk fields, omitted fields are kept. Cancelled talks can not be updated.",
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"patch": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(types.TalkPatch),
+			},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			id, ok := params.Args["id"].(string)
+			if !ok {
+				return nil, errors.New("Invalid id")
+			}
+			id = types.LocalID("Talk", id)
+
+			patch, ok := params.Args["patch"].(map[string]interface{})
+			if !ok {
+				return nil, errors.New("Invalid patch")
+			}
+
+			ctxb := params.Context
+			current, err := speakerTalk(ctxb, ctx, params, id)
+			if err != nil {
+				return nil, err
+			}
+			if current.GetCancelled() {
+				return nil, errors.New("Talk is cancelled")
+			}
+
+			// Only the fields present in the patch are sent in the mask, so
+			// an empty string or list clears a field.
+			talk := &talks.Talk{}
+			mask := &fieldmaskpb.FieldMask{}
+			if v, ok := patch["title"]; ok {
+				title, ok := v.(string)
+				if !ok || title == "" {
+					return nil, errors.New("Invalid title")
+				}
+				talk.Title = title
+				mask.Paths = append(mask.Paths, "title")
+			}
+			if v, ok := patch["description"]; ok {
+				talk.Description, _ = v.(string)
+				mask.Paths = append(mask.Paths, "description")
+			}
+			if v, ok := patch["repository"]; ok {
+				talk.Repository, _ = v.(string)
+				mask.Paths = append(mask.Paths, "repository")
+			}
+			if v, ok := patch["date"]; ok {
+				date, ok := v.(time.Time)
+				if !ok {
+					return nil, errors.New("Invalid date")
+				}
+				talk.Date = date.Unix()
+				mask.Paths = append(mask.Paths, "date")
+			}
+			if v, ok := patch["tags"]; ok {
+				talk.Tags = types.JoinTags(v)
+				mask.Paths = append(mask.Paths, "tags")
+			}
+			_, resized := patch["capacity"]
+			if resized {
+				capacity, ok := patch["capacity"].(int)
+				if !ok || capacity < 0 {
+					return nil, errors.New("Invalid capacity")
+				}
+				talk.Capacity = int64(capacity)
+				mask.Paths = append(mask.Paths, "capacity")
+
+				if talk.Capacity > 0 {
+					if err := checkCapacity(ctxb, ctx, id, talk.Capacity); err != nil {
+						return nil, err
+					}
+				}
+				clearAssistants(params, id)
+			}
+
+			t, err := ctx.TalkService.Update(ctxb, &talks.UpdateRequest{
+				TalkId:     id,
+				Talk:       talk,
+				UpdateMask: mask,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if resized {
+				if err := promoteWaitlist(ctxb, ctx, t.GetTalk()); err != nil {
+					return nil, err
+				}
+			}
+
+			return t.Talk, nil
+		},
+	}
+}
+
+// DeleteTalk removes a talk and its registrations, only its speaker can delete
+// it.
+func DeleteTalk(ctx *graph.Context) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(types.Talk),
+		Description: "Delete talk by id",
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			id, ok := params.Args["id"].(string)
+			if !ok {
+				return nil, errors.New("Invalid id")
+			}
+			id = types.LocalID("Talk", id)
+
+			ctxb := params.Context
+			if _, err := speakerTalk(ctxb, ctx, params, id); err != nil {
+				return nil, err
+			}
+
+			t, err := ctx.TalkService.Delete(ctxb, &talks.DeleteRequest{
+				TalkId: id,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			// The talk is gone, registrations left behind can not be
+			// reached anymore so the deletion still succeeds.
+			if err := deleteAssistants(ctxb, ctx, params, id); err != nil {
+				slog.ErrorContext(ctxb, "deleteTalk: delete assistants failed", "talk_id", id, "error", err)
+			}
+
+			return t.Talk, nil
+		},
+	}
+}
+
+// CancelTalk marks a talk as cancelled, only its speaker can cancel it.
+func CancelTalk(ctx *graph.Context) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(types.Talk),
+		Description: "Cancel talk by id",
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"reason": &graphql.ArgumentConfig{
+				Type: graphql.String,
+			},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			id, ok := params.Args["id"].(string)
+			if !ok {
+				return nil, errors.New("Invalid id")
+			}
+			id = types.LocalID("Talk", id)
+
+			reason, _ := params.Args["reason"].(string)
+
+			ctxb := params.Context
+			if _, err := speakerTalk(ctxb, ctx, params, id); err != nil {
+				return nil, err
+			}
+
+			t, err := ctx.TalkService.Cancel(ctxb, &talks.CancelRequest{
+				TalkId: id,
+				Reason: reason,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return t.Talk, nil
+		},
+	}
+}
+
+// speakerTalk returns the talk if the authenticated user is its speaker.
+func speakerTalk(ctxb context.Context, ctx *graph.Context, params graphql.ResolveParams, talkID string) (*talks.Talk, error) {
+	userID, err := currentUser(params)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := ctx.TalkService.Get(ctxb, &talks.GetRequest{
+		TalkId: talkID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if t.GetTalk().GetUserId() != userID {
+		return nil, errors.New("Only the speaker can change this talk")
+	}
+
+	return t.GetTalk(), nil
+}
+
+// RegisterTalk register a user into talk, once the talk is full users are
+// placed on its waitlist. Registering twice returns the existing registration.
+// Only admins and services authenticated by an API key can register another
+// user than the authenticated one.
 func RegisterTalk(ctx *graph.Context) *graphql.Field {
 	return &graphql.Field{
-		Type:        types.Talk,
-		Description: "Register a user into talk.",
+		Type:        graphql.NewNonNull(types.Registration),
+		Description: "Register a user into talk, defaults to the authenticated user, only admins and services can register another user. Users are waitlisted once the talk is full.",
 		Args: graphql.FieldConfigArgument{
 			"talk_id": &graphql.ArgumentConfig{
-				Type: graphql.String,
+				Type: graphql.NewNonNull(graphql.ID),
 			},
 			"user_id": &graphql.ArgumentConfig{
-				Type: graphql.String,
+				Type: graphql.ID,
 			},
 		},
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
@@ -109,13 +306,23 @@ func RegisterTalk(ctx *graph.Context) *graphql.Field {
 			if !ok {
 				return nil, errors.New("Invalid params")
 			}
+			talkID = types.LocalID("Talk", talkID)
 
 			userID, ok := params.Args["user_id"].(string)
-			if !ok {
-				return nil, errors.New("Invalid params")
+			if ok {
+				if !onBehalf(params) {
+					return nil, status.Error(codes.PermissionDenied, "Only admins and services can register another user")
+				}
+			} else {
+				current, err := currentUser(params)
+				if err != nil {
+					return nil, err
+				}
+				userID = current
 			}
+			userID = types.LocalID("User", userID)
 
-			ctxa := context.Background()
+			ctxa := params.Context
 			optsa := &talks.GetRequest{
 				TalkId: talkID,
 			}
@@ -123,6 +330,20 @@ func RegisterTalk(ctx *graph.Context) *graphql.Field {
 			if err != nil {
 				return nil, err
 			}
+			if t.GetTalk().GetCancelled() {
+				return nil, errors.New("Talk is cancelled")
+			}
+
+			a, err := registration(ctxa, ctx, talkID, userID)
+			if err != nil {
+				return nil, err
+			}
+			if a != nil {
+				return &types.RegistrationSource{
+					Talk:      t.GetTalk(),
+					Assistant: a,
+				}, nil
+			}
 
 			opts := &assistants.CreateRequest{
 				Data: &assistants.Assistant{
@@ -130,14 +351,99 @@ func RegisterTalk(ctx *graph.Context) *graphql.Field {
 					Assistant: userID,
 					TalkId:    talkID,
 				},
+				Capacity: t.GetTalk().GetCapacity(),
 			}
 
+			clearAssistants(params, talkID)
 			u, err := ctx.AssistantsService.Create(ctxa, opts)
+			if status.Code(err) == codes.AlreadyExists {
+				// A concurrent request registered the user first.
+				a, err = registration(ctxa, ctx, talkID, userID)
+				if err == nil && a == nil {
+					err = status.Error(codes.Aborted, "Registration changed concurrently, try again")
+				}
+				if err != nil {
+					return nil, err
+				}
+				return &types.RegistrationSource{
+					Talk:      t.GetTalk(),
+					Assistant: a,
+				}, nil
+			}
 			if err != nil {
 				return nil, err
 			}
 
-			return u.Data, nil
+			return &types.RegistrationSource{
+				Talk:      t.GetTalk(),
+				Assistant: u.GetData(),
+			}, nil
+		},
+	}
+}
+
+// UnregisterTalk removes the authenticated user from a talk, the seat is
+// given to the next user on the waitlist.
+func UnregisterTalk(ctx *graph.Context) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(types.Registration),
+		Description: "Unregister the authenticated user from a talk.",
+		Args: graphql.FieldConfigArgument{
+			"talk_id": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			talkID, ok := params.Args["talk_id"].(string)
+			if !ok {
+				return nil, errors.New("Invalid params")
+			}
+			talkID = types.LocalID("Talk", talkID)
+
+			userID, err := currentUser(params)
+			if err != nil {
+				return nil, err
+			}
+
+			ctxb := params.Context
+			t, err := ctx.TalkService.Get(ctxb, &talks.GetRequest{
+				TalkId: talkID,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			aa, err := ctx.AssistantsService.Select(ctxb, &assistants.SelectRequest{
+				TalkId:    talkID,
+				Assistant: userID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if len(aa.GetData()) == 0 {
+				return nil, errors.New("User is not registered into this talk")
+			}
+
+			clearAssistants(params, talkID)
+			var removed *assistants.Assistant
+			for _, a := range aa.GetData() {
+				d, err := ctx.AssistantsService.Delete(ctxb, &assistants.DeleteRequest{
+					Id: a.GetId(),
+				})
+				if err != nil {
+					return nil, err
+				}
+				removed = d.GetData()
+			}
+
+			if err := promoteWaitlist(ctxb, ctx, t.GetTalk()); err != nil {
+				return nil, err
+			}
+
+			return &types.RegistrationSource{
+				Talk:      t.GetTalk(),
+				Assistant: removed,
+			}, nil
 		},
 	}
 }
@@ -1,7 +1,6 @@
 package mutation
 
 import (
-	"context"
 	"errors"
 
 	"github.com/go-toschool/sicily/graph"
@@ -17,7 +16,7 @@ func UpdateUser(ctx *graph.Context) *graphql.Field {
 		Description: "Update user by id",
 		Args: graphql.FieldConfigArgument{
 			"id": &graphql.ArgumentConfig{
-				Type: graphql.String,
+				Type: graphql.NewNonNull(graphql.ID),
 			},
 			"full_name": &graphql.ArgumentConfig{
 				Type: graphql.String,
@@ -28,13 +27,14 @@ func UpdateUser(ctx *graph.Context) *graphql.Field {
 			if !ok {
 				return nil, errors.New("Invalid params")
 			}
+			id = types.LocalID("User", id)
 
-			fullName, ok := params.Args["fullName"].(string)
+			fullName, ok := params.Args["full_name"].(string)
 			if !ok {
 				return nil, errors.New("Invalid params")
 			}
 
-			ctxb := context.Background()
+			ctxb := params.Context
 			opts := &citizens.UpdateRequest{
 				UserId: id,
 				Data: &citizens.Citizen{
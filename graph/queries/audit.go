@@ -0,0 +1,42 @@
+package queries
+
+import (
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/sicily/graph/types"
+	"github.com/graphql-go/graphql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxAuditEntries = 1000
+
+// GetAuditLog reads the most recent entries of the audit trail, for admins.
+func GetAuditLog(ctx *graph.Context) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.AuditEntry))),
+		Description: "Most recent mutations, newest first. Only for admins",
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{
+				Type:         graphql.Int,
+				DefaultValue: 50,
+			},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			if c, ok := graph.CallerFromContext(params.Context); !ok || !c.Admin {
+				return nil, status.Error(codes.PermissionDenied, "Only admins can read the audit log")
+			}
+
+			r, ok := ctx.Audit.(graph.AuditReader)
+			if !ok {
+				return nil, status.Error(codes.Unimplemented, "The audit log can not be read back")
+			}
+
+			limit, _ := params.Args["limit"].(int)
+			if limit <= 0 || limit > maxAuditEntries {
+				limit = maxAuditEntries
+			}
+
+			return r.Recent(params.Context, limit)
+		},
+	}
+}
@@ -0,0 +1,121 @@
+package queries
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/sicily/graph/types"
+	"github.com/go-toschool/syracuse/citizens"
+	"github.com/graphql-go/graphql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetNode refetches any object implementing Node by its global id.
+func GetNode(ctx *graph.Context) *graphql.Field {
+	return &graphql.Field{
+		Type:        types.Node,
+		Description: "Get any object by its global id",
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Global id of the object",
+			},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			id, ok := params.Args["id"].(string)
+			if !ok {
+				return nil, errors.New("Invalid params")
+			}
+
+			return fetchNode(params.Context, ctx, id)
+		},
+	}
+}
+
+// GetNodes refetches many objects implementing Node by their global ids, in
+// the order given. An object that can not be fetched is null, with an error
+// located at its index, and does not fail the others.
+func GetNodes(ctx *graph.Context) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(types.Node)),
+		Description: "Get objects by their global ids",
+		Args: graphql.FieldConfigArgument{
+			"ids": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+				Description: "Global ids of the objects",
+			},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			ids, ok := params.Args["ids"].([]interface{})
+			if !ok {
+				return nil, errors.New("Invalid params")
+			}
+
+			nodes := make([]interface{}, 0, len(ids))
+			for _, v := range ids {
+				id, ok := v.(string)
+				if !ok {
+					return nil, errors.New("Invalid params")
+				}
+
+				// graphql-go locates the error of a thunk at the list index.
+				n, err := fetchNode(params.Context, ctx, id)
+				nodes = append(nodes, func() (interface{}, error) {
+					return n, err
+				})
+			}
+
+			return nodes, nil
+		},
+	}
+}
+
+// fetchNode loads the object identified by globalID from the service that
+// stores its type.
+func fetchNode(ctxb context.Context, ctx *graph.Context, globalID string) (interface{}, error) {
+	typeName, id, err := types.FromGlobalID(globalID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typeName {
+	case "User":
+		u, err := ctx.UserService.Get(ctxb, &citizens.GetRequest{
+			UserId: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return u.GetData(), nil
+	case "Talk":
+		t, err := ctx.TalkService.Get(ctxb, &talks.GetRequest{
+			TalkId: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return t.GetTalk(), nil
+	case "Assistant":
+		a, err := ctx.AssistantsService.Get(ctxb, &assistants.GetRequest{
+			Id: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return a.GetData(), nil
+	case "Session":
+		// palermo only finds sessions by their credentials, the caller can
+		// only refetch its own.
+		c, ok := graph.CallerFromContext(ctxb)
+		if !ok || c.Session == nil || c.Session.GetId() != id {
+			return nil, status.Errorf(codes.NotFound, "session %q not found", id)
+		}
+		return c.Session, nil
+	default:
+		return nil, types.ErrInvalidGlobalID
+	}
+}
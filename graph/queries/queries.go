@@ -10,10 +10,13 @@ func Queries(ctx *graph.Context) *graphql.Object {
 	return graphql.NewObject(graphql.ObjectConfig{
 		Name: "Queries",
 		Fields: graphql.Fields{
-			"talk":  GetTalk(ctx),
-			"talks": GetTalks(ctx),
-			"user":  GetUser(ctx),
-			"users": GetUsers(ctx),
+			"audit_log": GetAuditLog(ctx),
+			"node":      GetNode(ctx),
+			"nodes":     GetNodes(ctx),
+			"talk":      GetTalk(ctx),
+			"talks":     GetTalks(ctx),
+			"user":      GetUser(ctx),
+			"users":     GetUsers(ctx),
 		},
 	})
 }
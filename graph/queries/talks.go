@@ -1,7 +1,6 @@
 package queries
 
 import (
-	"context"
 	"errors"
 
 	"github.com/go-toschool/platon/talks"
@@ -17,7 +16,7 @@ func GetTalk(ctx *graph.Context) *graphql.Field {
 		Description: "Get talk by id",
 		Args: graphql.FieldConfigArgument{
 			"id": &graphql.ArgumentConfig{
-				Type:        graphql.String,
+				Type:        graphql.NewNonNull(graphql.ID),
 				Description: "return taks information by id",
 			},
 		},
@@ -26,8 +25,9 @@ func GetTalk(ctx *graph.Context) *graphql.Field {
 			if !ok {
 				return nil, errors.New("Invalid params")
 			}
+			id = types.LocalID("Talk", id)
 
-			ctxb := context.Background()
+			ctxb := params.Context
 			opts := &talks.GetRequest{
 				TalkId: id,
 			}
@@ -44,10 +44,10 @@ func GetTalk(ctx *graph.Context) *graphql.Field {
 // GetTalks get a collection of talks
 func GetTalks(ctx *graph.Context) *graphql.Field {
 	return &graphql.Field{
-		Type:        graphql.NewList(types.Talk),
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.Talk))),
 		Description: "Get collection of talks",
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-			ctxb := context.Background()
+			ctxb := params.Context
 			opts := &talks.SelectRequest{}
 
 			uu, err := ctx.TalkService.Select(ctxb, opts)
@@ -1,10 +1,8 @@
 package queries
 
 import (
-	"context"
 	"errors"
 
-	"github.com/go-toschool/platon/talks"
 	"github.com/go-toschool/sicily"
 	"github.com/go-toschool/sicily/graph"
 	"github.com/go-toschool/sicily/graph/types"
@@ -17,14 +15,14 @@ const (
 	UserIDKey sicily.StringValueKey = "user_id"
 )
 
-// GetUser resolve user information and users talk from external services.
+// GetUser resolve user information from syracuse.
 func GetUser(ctx *graph.Context) *graphql.Field {
 	return &graphql.Field{
-		Type:        types.UserWithTalks,
+		Type:        types.User,
 		Description: "Full user data",
 		Args: graphql.FieldConfigArgument{
 			"id": &graphql.ArgumentConfig{
-				Type: graphql.String,
+				Type: graphql.ID,
 			},
 		},
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
@@ -33,7 +31,7 @@ func GetUser(ctx *graph.Context) *graphql.Field {
 				return nil, errors.New("Invalid params")
 			}
 
-			ctxb := context.Background()
+			ctxb := params.Context
 			opts := &citizens.GetRequest{
 				UserId: userID,
 			}
@@ -42,25 +40,7 @@ func GetUser(ctx *graph.Context) *graphql.Field {
 				return nil, err
 			}
 
-			topts := &talks.GetRequest{
-				UserId: u.GetData().GetId(),
-			}
-			t, err := ctx.TalkService.Get(ctxb, topts)
-			if err != nil {
-				return nil, err
-			}
-
-			data := new(struct {
-				User  *citizens.Citizen
-				Talks []*talks.Talk
-			})
-
-			talks := make([]*talks.Talk, 0)
-			talks = append(talks, t.GetTalk())
-			data.User = u.GetData()
-			data.Talks = talks
-
-			return data, nil
+			return u.GetData(), nil
 		},
 	}
 }
@@ -68,10 +48,10 @@ func GetUser(ctx *graph.Context) *graphql.Field {
 // GetUsers get a collection of users
 func GetUsers(ctx *graph.Context) *graphql.Field {
 	return &graphql.Field{
-		Type:        graphql.NewList(types.User),
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.User))),
 		Description: "Get collection of users",
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-			ctxb := context.Background()
+			ctxb := params.Context
 			opts := &citizens.SelectRequest{}
 			uu, err := ctx.UserService.Select(ctxb, opts)
 			if err != nil {
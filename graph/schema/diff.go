@@ -0,0 +1,394 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Level classifies how a schema change affects existing clients.
+type Level int
+
+const (
+	// Safe changes cannot break existing clients.
+	Safe Level = iota
+	// Dangerous changes are valid for existing queries but may change the
+	// behaviour of clients, e.g. a new enum value they do not handle.
+	Dangerous
+	// Breaking changes make existing queries fail.
+	Breaking
+)
+
+func (l Level) String() string {
+	switch l {
+	case Breaking:
+		return "BREAKING"
+	case Dangerous:
+		return "DANGEROUS"
+	default:
+		return "SAFE"
+	}
+}
+
+// Change is a difference between two versions of a schema.
+type Change struct {
+	Level Level
+	// Path is the coordinate of the changed element, e.g. "Talk.date" or
+	// "Queries.talk(id:)".
+	Path    string
+	Message string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%-9s %s: %s", c.Level, c.Path, c.Message)
+}
+
+// Diff compares two schemas written in the schema definition language and
+// returns the changes needed to go from old to new, most severe first.
+func Diff(old, new string) ([]Change, error) {
+	before, err := parse("old schema", old)
+	if err != nil {
+		return nil, err
+	}
+	after, err := parse("new schema", new)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &differ{}
+	d.types(before, after)
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].Level > d.changes[j].Level
+	})
+
+	return d.changes, nil
+}
+
+// HasBreaking reports whether any change is breaking.
+func HasBreaking(changes []Change) bool {
+	for _, c := range changes {
+		if c.Level == Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// definition is the part of a type definition relevant to clients.
+type definition struct {
+	kind   string
+	fields map[string]*field
+	// members holds enum values, union members or implemented interfaces
+	// depending on kind.
+	members map[string]bool
+}
+
+type field struct {
+	typ          ast.Type
+	args         map[string]*field
+	defaultValue string
+}
+
+func parse(name, sdl string) (map[string]*definition, error) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(sdl), Name: name}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make(map[string]*definition)
+	for _, node := range doc.Definitions {
+		switch n := node.(type) {
+		case *ast.ObjectDefinition:
+			d := newDefinition("object")
+			for _, f := range n.Fields {
+				d.fields[f.Name.Value] = newField(f.Type, f.Arguments)
+			}
+			for _, i := range n.Interfaces {
+				d.members[i.Name.Value] = true
+			}
+			defs[n.Name.Value] = d
+		case *ast.InterfaceDefinition:
+			d := newDefinition("interface")
+			for _, f := range n.Fields {
+				d.fields[f.Name.Value] = newField(f.Type, f.Arguments)
+			}
+			defs[n.Name.Value] = d
+		case *ast.InputObjectDefinition:
+			d := newDefinition("input")
+			for _, f := range n.Fields {
+				d.fields[f.Name.Value] = newInput(f)
+			}
+			defs[n.Name.Value] = d
+		case *ast.UnionDefinition:
+			d := newDefinition("union")
+			for _, t := range n.Types {
+				d.members[t.Name.Value] = true
+			}
+			defs[n.Name.Value] = d
+		case *ast.EnumDefinition:
+			d := newDefinition("enum")
+			for _, v := range n.Values {
+				d.members[v.Name.Value] = true
+			}
+			defs[n.Name.Value] = d
+		case *ast.ScalarDefinition:
+			defs[n.Name.Value] = newDefinition("scalar")
+		}
+	}
+
+	return defs, nil
+}
+
+func newDefinition(kind string) *definition {
+	return &definition{
+		kind:    kind,
+		fields:  make(map[string]*field),
+		members: make(map[string]bool),
+	}
+}
+
+func newField(t ast.Type, args []*ast.InputValueDefinition) *field {
+	f := &field{
+		typ:  t,
+		args: make(map[string]*field),
+	}
+	for _, a := range args {
+		f.args[a.Name.Value] = newInput(a)
+	}
+
+	return f
+}
+
+func newInput(v *ast.InputValueDefinition) *field {
+	f := &field{typ: v.Type}
+	if v.DefaultValue != nil {
+		f.defaultValue = fmt.Sprintf("%v", printer.Print(v.DefaultValue))
+	}
+
+	return f
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(level Level, path, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{
+		Level:   level,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *differ) types(before, after map[string]*definition) {
+	for _, name := range typeNames(before, after) {
+		old, okOld := before[name]
+		cur, okNew := after[name]
+		switch {
+		case !okNew:
+			d.add(Breaking, name, "%s removed", old.kind)
+		case !okOld:
+			d.add(Safe, name, "%s added", cur.kind)
+		case old.kind != cur.kind:
+			d.add(Breaking, name, "changed from %s to %s", old.kind, cur.kind)
+		default:
+			d.definition(name, old, cur)
+		}
+	}
+}
+
+func (d *differ) definition(name string, old, cur *definition) {
+	switch old.kind {
+	case "object", "interface":
+		d.outputFields(name, old.fields, cur.fields)
+		d.members(name, old.members, cur.members, "interface", Breaking, Dangerous)
+	case "input":
+		d.inputFields(name, old.fields, cur.fields)
+	case "union":
+		d.members(name, old.members, cur.members, "member", Breaking, Dangerous)
+	case "enum":
+		d.members(name, old.members, cur.members, "value", Breaking, Dangerous)
+	}
+}
+
+func (d *differ) members(name string, old, cur map[string]bool, what string, removed, added Level) {
+	for _, m := range memberNames(old, cur) {
+		switch {
+		case !cur[m]:
+			d.add(removed, name, "%s %s removed", what, m)
+		case !old[m]:
+			d.add(added, name, "%s %s added", what, m)
+		}
+	}
+}
+
+func (d *differ) outputFields(typeName string, old, cur map[string]*field) {
+	for _, name := range fieldNames(old, cur) {
+		path := typeName + "." + name
+		before, okOld := old[name]
+		after, okNew := cur[name]
+		switch {
+		case !okNew:
+			d.add(Breaking, path, "field removed")
+			continue
+		case !okOld:
+			d.add(Safe, path, "field added")
+			continue
+		}
+
+		if !safeOutput(before.typ, after.typ) {
+			d.add(Breaking, path, "type changed from %s to %s", typeString(before.typ), typeString(after.typ))
+		} else if typeString(before.typ) != typeString(after.typ) {
+			d.add(Safe, path, "type changed from %s to %s", typeString(before.typ), typeString(after.typ))
+		}
+		d.arguments(path, before.args, after.args)
+	}
+}
+
+func (d *differ) arguments(fieldPath string, old, cur map[string]*field) {
+	for _, name := range fieldNames(old, cur) {
+		path := fmt.Sprintf("%s(%s:)", fieldPath, name)
+		d.input(path, "argument", old[name], cur[name])
+	}
+}
+
+func (d *differ) inputFields(typeName string, old, cur map[string]*field) {
+	for _, name := range fieldNames(old, cur) {
+		d.input(typeName+"."+name, "input field", old[name], cur[name])
+	}
+}
+
+// input compares an argument or an input field, either may be nil when it
+// was added or removed.
+func (d *differ) input(path, what string, before, after *field) {
+	switch {
+	case after == nil:
+		d.add(Breaking, path, "%s removed", what)
+		return
+	case before == nil:
+		if required(after) {
+			d.add(Breaking, path, "required %s added", what)
+		} else {
+			d.add(Dangerous, path, "optional %s added", what)
+		}
+		return
+	}
+
+	if !safeInput(before.typ, after.typ) {
+		d.add(Breaking, path, "type changed from %s to %s", typeString(before.typ), typeString(after.typ))
+	} else if typeString(before.typ) != typeString(after.typ) {
+		d.add(Safe, path, "type changed from %s to %s", typeString(before.typ), typeString(after.typ))
+	}
+	if before.defaultValue != after.defaultValue {
+		d.add(Dangerous, path, "default value changed from %q to %q", before.defaultValue, after.defaultValue)
+	}
+}
+
+func required(f *field) bool {
+	_, nonNull := f.typ.(*ast.NonNull)
+	return nonNull && f.defaultValue == ""
+}
+
+// safeOutput reports whether clients reading a field of type old can read
+// it as type cur, i.e. cur is the same type or a non-null version of it.
+func safeOutput(old, cur ast.Type) bool {
+	switch o := old.(type) {
+	case *ast.NonNull:
+		c, ok := cur.(*ast.NonNull)
+		return ok && safeOutput(o.Type, c.Type)
+	case *ast.List:
+		if c, ok := cur.(*ast.List); ok {
+			return safeOutput(o.Type, c.Type)
+		}
+	case *ast.Named:
+		if c, ok := cur.(*ast.Named); ok {
+			return o.Name.Value == c.Name.Value
+		}
+	}
+
+	c, ok := cur.(*ast.NonNull)
+	return ok && safeOutput(old, c.Type)
+}
+
+// safeInput reports whether values sent by clients for type old are still
+// valid for type cur, i.e. cur is the same type or a nullable version of it.
+func safeInput(old, cur ast.Type) bool {
+	switch o := old.(type) {
+	case *ast.NonNull:
+		if c, ok := cur.(*ast.NonNull); ok {
+			return safeInput(o.Type, c.Type)
+		}
+		return safeInput(o.Type, cur)
+	case *ast.List:
+		c, ok := cur.(*ast.List)
+		return ok && safeInput(o.Type, c.Type)
+	case *ast.Named:
+		c, ok := cur.(*ast.Named)
+		return ok && o.Name.Value == c.Name.Value
+	}
+
+	return false
+}
+
+func typeString(t ast.Type) string {
+	switch t := t.(type) {
+	case *ast.NonNull:
+		return typeString(t.Type) + "!"
+	case *ast.List:
+		return "[" + typeString(t.Type) + "]"
+	case *ast.Named:
+		return t.Name.Value
+	}
+
+	return ""
+}
+
+func typeNames(a, b map[string]*definition) []string {
+	names := make([]string, 0, len(a)+len(b))
+	for _, m := range []map[string]*definition{a, b} {
+		for name := range m {
+			names = append(names, name)
+		}
+	}
+	return unique(names)
+}
+
+func fieldNames(a, b map[string]*field) []string {
+	names := make([]string, 0, len(a)+len(b))
+	for _, m := range []map[string]*field{a, b} {
+		for name := range m {
+			names = append(names, name)
+		}
+	}
+	return unique(names)
+}
+
+func memberNames(a, b map[string]bool) []string {
+	names := make([]string, 0, len(a)+len(b))
+	for _, m := range []map[string]bool{a, b} {
+		for name := range m {
+			names = append(names, name)
+		}
+	}
+	return unique(names)
+}
+
+// unique sorts names removing duplicates.
+func unique(names []string) []string {
+	sort.Strings(names)
+
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if len(out) == 0 || out[len(out)-1] != name {
+			out = append(out, name)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,271 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []Change
+	}{
+		{
+			"unchanged",
+			`type Talk { title: String! }`,
+			`type Talk { title: String! }`,
+			nil,
+		},
+		{
+			"type added",
+			`type Talk { title: String }`,
+			`type Talk { title: String } scalar Date`,
+			[]Change{{Safe, "Date", "scalar added"}},
+		},
+		{
+			"type removed",
+			`type Talk { title: String } scalar Date`,
+			`type Talk { title: String }`,
+			[]Change{{Breaking, "Date", "scalar removed"}},
+		},
+		{
+			"kind changed",
+			`type Talk { title: String }`,
+			`interface Talk { title: String }`,
+			[]Change{{Breaking, "Talk", "changed from object to interface"}},
+		},
+		{
+			"field added",
+			`type Talk { title: String }`,
+			`type Talk { title: String date: String }`,
+			[]Change{{Safe, "Talk.date", "field added"}},
+		},
+		{
+			"field removed",
+			`type Talk { title: String date: String }`,
+			`type Talk { title: String }`,
+			[]Change{{Breaking, "Talk.date", "field removed"}},
+		},
+
+		// Clients can always read a non-null value, not always a null one.
+		{
+			"output made non-null",
+			`type Talk { title: String }`,
+			`type Talk { title: String! }`,
+			[]Change{{Safe, "Talk.title", "type changed from String to String!"}},
+		},
+		{
+			"output made nullable",
+			`type Talk { title: String! }`,
+			`type Talk { title: String }`,
+			[]Change{{Breaking, "Talk.title", "type changed from String! to String"}},
+		},
+		{
+			"output items made non-null",
+			`type Talk { tags: [String] }`,
+			`type Talk { tags: [String!]! }`,
+			[]Change{{Safe, "Talk.tags", "type changed from [String] to [String!]!"}},
+		},
+		{
+			"output items made nullable",
+			`type Talk { tags: [String!] }`,
+			`type Talk { tags: [String] }`,
+			[]Change{{Breaking, "Talk.tags", "type changed from [String!] to [String]"}},
+		},
+		{
+			"output type changed",
+			`type Talk { capacity: Int }`,
+			`type Talk { capacity: String }`,
+			[]Change{{Breaking, "Talk.capacity", "type changed from Int to String"}},
+		},
+
+		// Clients may send a null value for a nullable input, not for a
+		// non-null one.
+		{
+			"input made non-null",
+			`input TalkPatch { title: String }`,
+			`input TalkPatch { title: String! }`,
+			[]Change{{Breaking, "TalkPatch.title", "type changed from String to String!"}},
+		},
+		{
+			"input made nullable",
+			`input TalkPatch { title: String! }`,
+			`input TalkPatch { title: String }`,
+			[]Change{{Safe, "TalkPatch.title", "type changed from String! to String"}},
+		},
+		{
+			"input items made nullable",
+			`input TalkPatch { tags: [String!]! }`,
+			`input TalkPatch { tags: [String] }`,
+			[]Change{{Safe, "TalkPatch.tags", "type changed from [String!]! to [String]"}},
+		},
+		{
+			"input made a list",
+			`input TalkPatch { tags: String }`,
+			`input TalkPatch { tags: [String] }`,
+			[]Change{{Breaking, "TalkPatch.tags", "type changed from String to [String]"}},
+		},
+		{
+			"required input field added",
+			`input TalkPatch { title: String }`,
+			`input TalkPatch { title: String date: String! }`,
+			[]Change{{Breaking, "TalkPatch.date", "required input field added"}},
+		},
+		{
+			"input field removed",
+			`input TalkPatch { title: String date: String }`,
+			`input TalkPatch { title: String }`,
+			[]Change{{Breaking, "TalkPatch.date", "input field removed"}},
+		},
+
+		// Arguments follow the rules of inputs.
+		{
+			"required argument added",
+			`type Queries { talks: [String] }`,
+			`type Queries { talks(limit: Int!): [String] }`,
+			[]Change{{Breaking, "Queries.talks(limit:)", "required argument added"}},
+		},
+		{
+			"optional argument added",
+			`type Queries { talks: [String] }`,
+			`type Queries { talks(limit: Int): [String] }`,
+			[]Change{{Dangerous, "Queries.talks(limit:)", "optional argument added"}},
+		},
+		{
+			"non-null argument added with a default",
+			`type Queries { talks: [String] }`,
+			`type Queries { talks(limit: Int! = 10): [String] }`,
+			[]Change{{Dangerous, "Queries.talks(limit:)", "optional argument added"}},
+		},
+		{
+			"argument removed",
+			`type Queries { talks(limit: Int): [String] }`,
+			`type Queries { talks: [String] }`,
+			[]Change{{Breaking, "Queries.talks(limit:)", "argument removed"}},
+		},
+		{
+			"argument made required",
+			`type Queries { talks(limit: Int): [String] }`,
+			`type Queries { talks(limit: Int!): [String] }`,
+			[]Change{{Breaking, "Queries.talks(limit:)", "type changed from Int to Int!"}},
+		},
+		{
+			"argument made optional",
+			`type Queries { talks(limit: Int!): [String] }`,
+			`type Queries { talks(limit: Int): [String] }`,
+			[]Change{{Safe, "Queries.talks(limit:)", "type changed from Int! to Int"}},
+		},
+
+		// Clients relying on a default get another behaviour.
+		{
+			"default value changed",
+			`type Queries { talks(limit: Int = 10): [String] }`,
+			`type Queries { talks(limit: Int = 20): [String] }`,
+			[]Change{{Dangerous, "Queries.talks(limit:)", `default value changed from "10" to "20"`}},
+		},
+		{
+			"default value added",
+			`input TalkPatch { capacity: Int }`,
+			`input TalkPatch { capacity: Int = 10 }`,
+			[]Change{{Dangerous, "TalkPatch.capacity", `default value changed from "" to "10"`}},
+		},
+		{
+			"default value removed",
+			`input TalkPatch { capacity: Int = 10 }`,
+			`input TalkPatch { capacity: Int }`,
+			[]Change{{Dangerous, "TalkPatch.capacity", `default value changed from "10" to ""`}},
+		},
+
+		// Clients may not handle a new member, and queries using a removed
+		// one fail.
+		{
+			"enum value added",
+			`enum Status { CONFIRMED }`,
+			`enum Status { CONFIRMED WAITLISTED }`,
+			[]Change{{Dangerous, "Status", "value WAITLISTED added"}},
+		},
+		{
+			"enum value removed",
+			`enum Status { CONFIRMED WAITLISTED }`,
+			`enum Status { CONFIRMED }`,
+			[]Change{{Breaking, "Status", "value WAITLISTED removed"}},
+		},
+		{
+			"union member added",
+			`union Result = Talk`,
+			`union Result = Talk | User`,
+			[]Change{{Dangerous, "Result", "member User added"}},
+		},
+		{
+			"union member removed",
+			`union Result = Talk | User`,
+			`union Result = Talk`,
+			[]Change{{Breaking, "Result", "member User removed"}},
+		},
+		{
+			"interface added",
+			`type Talk { id: ID }`,
+			`type Talk implements Node { id: ID }`,
+			[]Change{{Dangerous, "Talk", "interface Node added"}},
+		},
+		{
+			"interface removed",
+			`type Talk implements Node { id: ID }`,
+			`type Talk { id: ID }`,
+			[]Change{{Breaking, "Talk", "interface Node removed"}},
+		},
+
+		{
+			"most severe first",
+			`enum Status { CONFIRMED } type Talk { title: String }`,
+			`enum Status { CONFIRMED WAITLISTED } type Talk { date: String title: String }`,
+			[]Change{
+				{Dangerous, "Status", "value WAITLISTED added"},
+				{Safe, "Talk.date", "field added"},
+			},
+		},
+		{
+			"breaking before dangerous",
+			`enum Status { CONFIRMED } type Talk { title: String }`,
+			`enum Status { CONFIRMED WAITLISTED } type Talk { date: String }`,
+			[]Change{
+				{Breaking, "Talk.title", "field removed"},
+				{Dangerous, "Status", "value WAITLISTED added"},
+				{Safe, "Talk.date", "field added"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Diff(tt.old, tt.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(changes, tt.want) {
+				t.Fatalf("Diff() = %v, want %v", changes, tt.want)
+			}
+
+			breaking := false
+			for _, c := range tt.want {
+				breaking = breaking || c.Level == Breaking
+			}
+			if HasBreaking(changes) != breaking {
+				t.Fatalf("HasBreaking() = %v, want %v", !breaking, breaking)
+			}
+		})
+	}
+}
+
+func TestDiffSyntaxError(t *testing.T) {
+	if _, err := Diff(`type Talk {`, `type Talk { title: String }`); err == nil {
+		t.Fatal("no error for an invalid old schema")
+	}
+	if _, err := Diff(`type Talk { title: String }`, `type Talk {`); err == nil {
+		t.Fatal("no error for an invalid new schema")
+	}
+}
@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"context"
+
+	"github.com/go-toschool/sicily/graph"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// errorsExtension adds a code to the extensions of the errors raised by
+// resolvers that do not carry extensions already.
+type errorsExtension struct{}
+
+func (e *errorsExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+func (e *errorsExtension) Name() string {
+	return "errorCodes"
+}
+
+func (e *errorsExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (e *errorsExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (e *errorsExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(result *graphql.Result) {
+		for i, fe := range result.Errors {
+			if fe.Extensions != nil {
+				continue
+			}
+
+			located, ok := fe.OriginalError().(*gqlerrors.Error)
+			if !ok || located.OriginalError == nil {
+				continue
+			}
+
+			// graphql-go formats the errors of thunks before locating them.
+			err := located.OriginalError
+			if formatted, ok := err.(gqlerrors.FormattedError); ok && formatted.OriginalError() != nil {
+				err = formatted.OriginalError()
+			}
+
+			result.Errors[i].Extensions = map[string]interface{}{
+				"code": graph.ErrorCode(err),
+			}
+		}
+	}
+}
+
+func (e *errorsExtension) ResolveFieldDidStart(ctx context.Context, _ *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	return ctx, func(interface{}, error) {}
+}
+
+func (e *errorsExtension) HasResult() bool {
+	return false
+}
+
+func (e *errorsExtension) GetResult(context.Context) interface{} {
+	return nil
+}
@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"context"
+
+	"github.com/go-toschool/sicily/graph"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// contextExtension stores the graph context and fresh user and assistants
+// loaders in the context of every request executed against the schema.
+type contextExtension struct {
+	ctx *graph.Context
+}
+
+func (e *contextExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = graph.NewContext(ctx, e.ctx)
+	ctx = graph.WithUserLoader(ctx, graph.NewUserLoader(e.ctx.UserService))
+	return graph.WithAssistantsLoader(ctx, graph.NewAssistantsLoader(e.ctx.AssistantsService))
+}
+
+func (e *contextExtension) Name() string {
+	return "graphContext"
+}
+
+func (e *contextExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (e *contextExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (e *contextExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (e *contextExtension) ResolveFieldDidStart(ctx context.Context, _ *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	return ctx, func(interface{}, error) {}
+}
+
+func (e *contextExtension) HasResult() bool {
+	return false
+}
+
+func (e *contextExtension) GetResult(context.Context) interface{} {
+	return nil
+}
@@ -0,0 +1,31 @@
+// Package schema builds the gateway GraphQL schema and compares it with
+// previous versions to detect changes that break clients.
+package schema
+
+import (
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/sicily/graph/cache"
+	"github.com/go-toschool/sicily/graph/mutation"
+	"github.com/go-toschool/sicily/graph/queries"
+	"github.com/go-toschool/sicily/graph/types"
+	"github.com/graphql-go/graphql"
+)
+
+// New builds the gateway schema resolving against the services in ctx.
+func New(ctx *graph.Context) (graphql.Schema, error) {
+	s, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queries.Queries(ctx),
+		Mutation: mutation.Mutations(ctx),
+		// Session is only reachable through the Node interface.
+		Types: []graphql.Type{types.Session},
+	})
+	if err != nil {
+		return s, err
+	}
+
+	traceResolvers(s)
+
+	s.AddExtensions(&contextExtension{ctx}, cache.NewExtension(types.Cache), &errorsExtension{})
+
+	return s, nil
+}
@@ -0,0 +1,264 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// specifiedScalars are the scalars every GraphQL server provides, they are
+// not printed.
+var specifiedScalars = map[string]bool{
+	"String":  true,
+	"Int":     true,
+	"Float":   true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// Print returns the schema in the GraphQL schema definition language. Types,
+// fields and arguments are sorted by name so the output is stable.
+func Print(s graphql.Schema) string {
+	blocks := make([]string, 0)
+	if def := printSchemaDefinition(s); def != "" {
+		blocks = append(blocks, def)
+	}
+
+	names := make([]string, 0, len(s.TypeMap()))
+	for name := range s.TypeMap() {
+		if strings.HasPrefix(name, "__") || specifiedScalars[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		blocks = append(blocks, printType(s.TypeMap()[name]))
+	}
+
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+func printSchemaDefinition(s graphql.Schema) string {
+	query, mutation, subscription := s.QueryType(), s.MutationType(), s.SubscriptionType()
+	if (query == nil || query.Name() == "Query") &&
+		(mutation == nil || mutation.Name() == "Mutation") &&
+		(subscription == nil || subscription.Name() == "Subscription") {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("schema {\n")
+	if query != nil {
+		fmt.Fprintf(&b, "  query: %s\n", query.Name())
+	}
+	if mutation != nil {
+		fmt.Fprintf(&b, "  mutation: %s\n", mutation.Name())
+	}
+	if subscription != nil {
+		fmt.Fprintf(&b, "  subscription: %s\n", subscription.Name())
+	}
+	b.WriteString("}")
+
+	return b.String()
+}
+
+func printType(t graphql.Type) string {
+	var b strings.Builder
+	printDescription(&b, t.Description(), "")
+
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		fmt.Fprintf(&b, "scalar %s", t.Name())
+	case *graphql.Object:
+		fmt.Fprintf(&b, "type %s", t.Name())
+		if len(t.Interfaces()) > 0 {
+			names := make([]string, 0, len(t.Interfaces()))
+			for _, i := range t.Interfaces() {
+				names = append(names, i.Name())
+			}
+			sort.Strings(names)
+			fmt.Fprintf(&b, " implements %s", strings.Join(names, " & "))
+		}
+		printFields(&b, t.Fields())
+	case *graphql.Interface:
+		fmt.Fprintf(&b, "interface %s", t.Name())
+		printFields(&b, t.Fields())
+	case *graphql.Union:
+		names := make([]string, 0, len(t.Types()))
+		for _, o := range t.Types() {
+			names = append(names, o.Name())
+		}
+		sort.Strings(names)
+		fmt.Fprintf(&b, "union %s = %s", t.Name(), strings.Join(names, " | "))
+	case *graphql.Enum:
+		fmt.Fprintf(&b, "enum %s {\n", t.Name())
+		values := t.Values()
+		sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+		for _, v := range values {
+			printDescription(&b, v.Description, "  ")
+			fmt.Fprintf(&b, "  %s%s\n", v.Name, printDeprecated(v.DeprecationReason))
+		}
+		b.WriteString("}")
+	case *graphql.InputObject:
+		fmt.Fprintf(&b, "input %s {\n", t.Name())
+		fields := t.Fields()
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			f := fields[name]
+			printDescription(&b, f.Description(), "  ")
+			fmt.Fprintf(&b, "  %s: %s%s\n", f.Name(), f.Type, printDefault(f.DefaultValue, f.Type))
+		}
+		b.WriteString("}")
+	}
+
+	return b.String()
+}
+
+func printFields(b *strings.Builder, fields graphql.FieldDefinitionMap) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString(" {\n")
+	for _, name := range names {
+		f := fields[name]
+		printDescription(b, f.Description, "  ")
+		fmt.Fprintf(b, "  %s%s: %s%s\n", f.Name, printArgs(f.Args), f.Type, printDeprecated(f.DeprecationReason))
+	}
+	b.WriteString("}")
+}
+
+func printArgs(args []*graphql.Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	sorted := make([]*graphql.Argument, len(args))
+	copy(sorted, args)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+
+	described := false
+	for _, a := range sorted {
+		described = described || a.Description() != ""
+	}
+
+	if !described {
+		parts := make([]string, 0, len(sorted))
+		for _, a := range sorted {
+			parts = append(parts, fmt.Sprintf("%s: %s%s", a.Name(), a.Type, printDefault(a.DefaultValue, a.Type)))
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	}
+
+	var b strings.Builder
+	b.WriteString("(\n")
+	for _, a := range sorted {
+		printDescription(&b, a.Description(), "    ")
+		fmt.Fprintf(&b, "    %s: %s%s\n", a.Name(), a.Type, printDefault(a.DefaultValue, a.Type))
+	}
+	b.WriteString("  )")
+
+	return b.String()
+}
+
+func printDeprecated(reason string) string {
+	if reason == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" @deprecated(reason: %s)", quote(reason))
+}
+
+func printDefault(v interface{}, t graphql.Input) string {
+	if v == nil {
+		return ""
+	}
+
+	return " = " + printValue(v, t)
+}
+
+// printValue formats a Go default value as a GraphQL literal of type t.
+func printValue(v interface{}, t graphql.Input) string {
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		return printValue(v, nonNull.OfType)
+	}
+
+	switch t := t.(type) {
+	case *graphql.List:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return printValue(v, t.OfType)
+		}
+		items := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, printValue(rv.Index(i).Interface(), t.OfType))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *graphql.Enum:
+		for _, value := range t.Values() {
+			if reflect.DeepEqual(value.Value, v) {
+				return value.Name
+			}
+		}
+	case *graphql.InputObject:
+		if m, ok := v.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			parts := make([]string, 0, len(keys))
+			for _, k := range keys {
+				if f, ok := t.Fields()[k]; ok {
+					parts = append(parts, fmt.Sprintf("%s: %s", k, printValue(m[k], f.Type)))
+				}
+			}
+			return "{" + strings.Join(parts, ", ") + "}"
+		}
+	}
+
+	if s, ok := v.(string); ok {
+		return quote(s)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+func printDescription(b *strings.Builder, description, indent string) {
+	if description == "" {
+		return
+	}
+
+	if !strings.Contains(description, "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, quote(description))
+		return
+	}
+
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(description, "\n") {
+		if line == "" {
+			b.WriteString("\n")
+			continue
+		}
+		line = strings.Replace(line, `"""`, `\"""`, -1)
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
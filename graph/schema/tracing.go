@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/go-toschool/sicily/graph")
+
+// The object types are package globals shared by every schema built, traced
+// remembers the fields already wrapped so their resolutions are one span
+// however many schemas are built.
+var (
+	tracedMu sync.Mutex
+	traced   = make(map[*graphql.FieldDefinition]bool)
+)
+
+// traceResolvers wraps the resolvers of the root fields and of the fields
+// returning objects so each of their resolutions is a span, and the calls
+// made by the resolver are its children. Fields returning scalars read the
+// object resolved by their parent and are not traced.
+func traceResolvers(s graphql.Schema) {
+	tracedMu.Lock()
+	defer tracedMu.Unlock()
+
+	roots := make(map[string]bool)
+	for _, root := range []*graphql.Object{s.QueryType(), s.MutationType()} {
+		if root != nil {
+			roots[root.Name()] = true
+		}
+	}
+
+	for name, t := range s.TypeMap() {
+		o, ok := t.(*graphql.Object)
+		if !ok || strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		for fieldName, f := range o.Fields() {
+			if f.Resolve == nil || traced[f] || !roots[name] && !composite(f.Type) {
+				continue
+			}
+			f.Resolve = traceResolver(name+"."+fieldName, f.Resolve)
+			traced[f] = true
+		}
+	}
+}
+
+// traceResolver wraps resolve in a span named name. Resolvers returning a
+// thunk, like the loaded fields, are resolved once graphql-go calls it, so
+// their span ends when the thunk returns.
+func traceResolver(name string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx, span := tracer.Start(p.Context, name)
+		if p.Info.Path != nil {
+			span.SetAttributes(attribute.String("graphql.field.path", path(p.Info.Path.AsArray())))
+		}
+
+		p.Context = ctx
+		res, err := resolve(p)
+		thunk, ok := res.(func() (interface{}, error))
+		if err != nil || !ok {
+			end(span, err)
+			return res, err
+		}
+
+		return func() (interface{}, error) {
+			res, err := thunk()
+			end(span, err)
+			return res, err
+		}, nil
+	}
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func composite(t graphql.Output) bool {
+	switch graphql.GetNamed(t).(type) {
+	case *graphql.Object, *graphql.Interface, *graphql.Union:
+		return true
+	default:
+		return false
+	}
+}
+
+func path(keys []interface{}) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprint(k)
+	}
+	return strings.Join(parts, ".")
+}
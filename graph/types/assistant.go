@@ -0,0 +1,138 @@
+package types
+
+import (
+	"sort"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/platon/talks"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	// StatusConfirmed is the status of an assistant holding a seat.
+	StatusConfirmed = "confirmed"
+	// StatusWaitlisted is the status of an assistant waiting for a seat.
+	StatusWaitlisted = "waitlisted"
+)
+
+// RegistrationStatus tells whether an assistant holds a seat in the talk.
+var RegistrationStatus = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "RegistrationStatus",
+	Description: "Whether a registered user holds a seat in the talk",
+	Values: graphql.EnumValueConfigMap{
+		"CONFIRMED": &graphql.EnumValueConfig{
+			Value:       StatusConfirmed,
+			Description: "The user holds a seat in the talk",
+		},
+		"WAITLISTED": &graphql.EnumValueConfig{
+			Value:       StatusWaitlisted,
+			Description: "The talk is full, the user gets a seat when someone unregisters",
+		},
+	},
+})
+
+// Assistant is a user registered into a talk.
+var Assistant = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Assistant",
+	Description: "A user registered into a talk",
+	Interfaces:  []*graphql.Interface{Node},
+	IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+		_, ok := p.Value.(*assistants.Assistant)
+		return ok
+	},
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Globally unique assistant identifier",
+			Resolve:     assistantField(func(a *assistants.Assistant) interface{} { return GlobalID("Assistant", a.GetId()) }),
+		},
+		"talk_id": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Talk the user registered into",
+			Resolve:     assistantField(func(a *assistants.Assistant) interface{} { return GlobalID("Talk", a.GetTalkId()) }),
+		},
+		"user_id": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Registered user",
+			Resolve:     assistantField(func(a *assistants.Assistant) interface{} { return GlobalID("User", a.GetAssistant()) }),
+		},
+		"speaker": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Speaker of the talk",
+			Resolve:     assistantField(func(a *assistants.Assistant) interface{} { return GlobalID("User", a.GetSpeaker()) }),
+		},
+		"registrationStatus": &graphql.Field{
+			Type:        graphql.NewNonNull(RegistrationStatus),
+			Description: "Whether the user holds a seat or is waitlisted",
+			Resolve: assistantField(func(a *assistants.Assistant) interface{} {
+				if Waitlisted(a) {
+					return StatusWaitlisted
+				}
+				return StatusConfirmed
+			}),
+		},
+		"created_at": &graphql.Field{
+			Type:        DateTime,
+			Description: "When the user registered",
+			Resolve:     assistantField(func(a *assistants.Assistant) interface{} { return a.GetCreatedAt() }),
+		},
+		"updated_at": &graphql.Field{
+			Type:        DateTime,
+			Description: "When the registration last changed",
+			Resolve:     assistantField(func(a *assistants.Assistant) interface{} { return a.GetUpdatedAt() }),
+		},
+	},
+})
+
+// Registration is the result of registering into or unregistering from a
+// talk.
+var Registration = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Registration",
+	Description: "Result of registering into or unregistering from a talk",
+	Fields: graphql.Fields{
+		"talk": &graphql.Field{
+			Type:        graphql.NewNonNull(Talk),
+			Description: "The talk",
+			Resolve:     registrationField(func(r *RegistrationSource) interface{} { return r.Talk }),
+		},
+		"assistant": &graphql.Field{
+			Type:        graphql.NewNonNull(Assistant),
+			Description: "The registration",
+			Resolve:     registrationField(func(r *RegistrationSource) interface{} { return r.Assistant }),
+		},
+	},
+})
+
+// RegistrationSource is the value a Registration is resolved from.
+type RegistrationSource struct {
+	Talk      *talks.Talk
+	Assistant *assistants.Assistant
+}
+
+// Waitlisted reports whether a is waiting for a seat. Registrations created
+// before talks had a capacity have no status and hold a seat.
+func Waitlisted(a *assistants.Assistant) bool {
+	return a.GetStatus() == StatusWaitlisted
+}
+
+// SplitAttendees separates the assistants holding a seat from the waitlist,
+// both in registration order.
+func SplitAttendees(aa []*assistants.Assistant) (confirmed, waitlist []*assistants.Assistant) {
+	sorted := make([]*assistants.Assistant, len(aa))
+	copy(sorted, aa)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetCreatedAt() < sorted[j].GetCreatedAt()
+	})
+
+	confirmed = make([]*assistants.Assistant, 0, len(sorted))
+	waitlist = make([]*assistants.Assistant, 0)
+	for _, a := range sorted {
+		if Waitlisted(a) {
+			waitlist = append(waitlist, a)
+		} else {
+			confirmed = append(confirmed, a)
+		}
+	}
+
+	return confirmed, waitlist
+}
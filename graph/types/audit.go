@@ -0,0 +1,80 @@
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/go-toschool/sicily/graph"
+	"github.com/graphql-go/graphql"
+)
+
+// AuditEntry is a mutation recorded in the audit trail.
+var AuditEntry = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "AuditEntry",
+	Description: "A mutation recorded in the audit trail",
+	Fields: graphql.Fields{
+		"time": &graphql.Field{
+			Type:        graphql.NewNonNull(DateTime),
+			Description: "When the mutation ran",
+			Resolve:     auditField(func(e *graph.AuditEntry) interface{} { return e.Time }),
+		},
+		"user_id": &graphql.Field{
+			Type:        graphql.ID,
+			Description: "User running the mutation",
+			Resolve:     auditField(func(e *graph.AuditEntry) interface{} { return e.UserID }),
+		},
+		"principal": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Service running the mutation with an API key",
+			Resolve:     auditField(func(e *graph.AuditEntry) interface{} { return e.Principal }),
+		},
+		"request_id": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Id of the HTTP request",
+			Resolve:     auditField(func(e *graph.AuditEntry) interface{} { return e.RequestID }),
+		},
+		"operation": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Name of the GraphQL operation",
+			Resolve:     auditField(func(e *graph.AuditEntry) interface{} { return e.Operation }),
+		},
+		"mutation": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Mutation field executed",
+			Resolve:     auditField(func(e *graph.AuditEntry) interface{} { return e.Mutation }),
+		},
+		"arguments": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Arguments of the mutation as a JSON object, secrets are redacted",
+			Resolve: auditField(func(e *graph.AuditEntry) interface{} {
+				if len(e.Arguments) == 0 {
+					return nil
+				}
+				b, err := json.Marshal(e.Arguments)
+				if err != nil {
+					return nil
+				}
+				return string(b)
+			}),
+		},
+		"target_ids": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+			Description: "Ids of the objects targeted by the mutation",
+			Resolve: auditField(func(e *graph.AuditEntry) interface{} {
+				if e.TargetIDs == nil {
+					return []string{}
+				}
+				return e.TargetIDs
+			}),
+		},
+		"outcome": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "success or failure",
+			Resolve:     auditField(func(e *graph.AuditEntry) interface{} { return e.Outcome }),
+		},
+		"error_code": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Code of the error of a failed mutation",
+			Resolve:     auditField(func(e *graph.AuditEntry) interface{} { return e.ErrorCode }),
+		},
+	},
+})
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"time"
+
+	"github.com/go-toschool/sicily/graph/cache"
+)
+
+// Cache declares how long responses to queries can be cached and which
+// mutations make them stale.
+var Cache = cache.Config{
+	Hints: map[string]cache.Hint{
+		"Talk":                    {MaxAge: time.Minute},
+		"Talk.viewerRegistration": {MaxAge: time.Minute, Scope: cache.Private},
+		"User":                    {MaxAge: time.Minute},
+		"User.email":              {MaxAge: time.Minute, Scope: cache.Private},
+		"User.token":              {Scope: cache.Private},
+		"Assistant":               {MaxAge: 30 * time.Second},
+		"Node":                    {MaxAge: 30 * time.Second},
+		// Sessions hold the token of their user and are never stored.
+		"Session": {Scope: cache.Private},
+		// user is the authenticated user.
+		"Queries.user": {MaxAge: time.Minute, Scope: cache.Private},
+		// audit_log is read by admins and must show the latest entries.
+		"Queries.audit_log": {Scope: cache.Private},
+	},
+	Invalidates: map[string][]string{
+		"createTalk":     {"Talk"},
+		"updateTalk":     {"Talk"},
+		"deleteTalk":     {"Talk", "Assistant"},
+		"cancelTalk":     {"Talk"},
+		"registerTalk":   {"Talk", "Assistant"},
+		"unregisterTalk": {"Talk", "Assistant"},
+		"updateUser":     {"User"},
+	},
+}
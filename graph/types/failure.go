@@ -0,0 +1,29 @@
+package types
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// Failure tells what happens to the response when a field resolved from
+// another service than its parent fails.
+type Failure int
+
+const (
+	// NullField makes the field null, with a located error, and keeps the
+	// rest of the response: a talk is still returned when its attendees can
+	// not be loaded.
+	NullField Failure = iota
+	// NullParent makes the parent object null too, for fields without which
+	// the object is meaningless.
+	NullParent
+)
+
+// onFailure returns the type of a field of type t applying f: GraphQL nulls
+// out the parent of a failing non-null field, so the field is non-null only
+// for NullParent.
+func onFailure(f Failure, t graphql.Output) graphql.Output {
+	if f == NullParent {
+		return graphql.NewNonNull(t)
+	}
+	return t
+}
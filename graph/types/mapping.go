@@ -0,0 +1,81 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/palermo/auth"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/syracuse/citizens"
+	"github.com/graphql-go/graphql"
+)
+
+// Every field of the types in this package is resolved explicitly from the
+// protobuf message it represents, instead of relying on graphql-go matching
+// struct fields by name. The helpers below adapt a getter on the message to
+// a resolver, failing loudly when the source is not the expected message.
+
+func talkField(get func(*talks.Talk) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		t, ok := p.Source.(*talks.Talk)
+		if !ok {
+			return nil, sourceError("Talk", p)
+		}
+		return get(t), nil
+	}
+}
+
+func citizenField(get func(*citizens.Citizen) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		c, ok := p.Source.(*citizens.Citizen)
+		if !ok {
+			return nil, sourceError("User", p)
+		}
+		return get(c), nil
+	}
+}
+
+func assistantField(get func(*assistants.Assistant) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		a, ok := p.Source.(*assistants.Assistant)
+		if !ok {
+			return nil, sourceError("Assistant", p)
+		}
+		return get(a), nil
+	}
+}
+
+func sessionField(get func(*auth.Session) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		s, ok := p.Source.(*auth.Session)
+		if !ok {
+			return nil, sourceError("Session", p)
+		}
+		return get(s), nil
+	}
+}
+
+func registrationField(get func(*RegistrationSource) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		r, ok := p.Source.(*RegistrationSource)
+		if !ok {
+			return nil, sourceError("Registration", p)
+		}
+		return get(r), nil
+	}
+}
+
+func auditField(get func(*graph.AuditEntry) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		e, ok := p.Source.(*graph.AuditEntry)
+		if !ok {
+			return nil, sourceError("AuditEntry", p)
+		}
+		return get(e), nil
+	}
+}
+
+func sourceError(typeName string, p graphql.ResolveParams) error {
+	return fmt.Errorf("%s.%s: unexpected source %T", typeName, p.Info.FieldName, p.Source)
+}
@@ -0,0 +1,229 @@
+package types_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/palermo/auth"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily"
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/sicily/graph/schema"
+	"github.com/go-toschool/sicily/graph/types"
+	"github.com/go-toschool/sicily/mock"
+	"github.com/go-toschool/syracuse/citizens"
+	"github.com/graphql-go/graphql"
+)
+
+var (
+	talk = &talks.Talk{
+		Id:           "talk-1",
+		Title:        "Intro to gRPC",
+		Description:  "Services over HTTP/2",
+		Repository:   "https://github.com/go-toschool/grpc",
+		Date:         1552586400,
+		Tags:         "go, grpc",
+		UserId:       "user-1",
+		Capacity:     30,
+		Cancelled:    true,
+		CancelReason: "Speaker is sick",
+		CreatedAt:    1550000001,
+		UpdatedAt:    1550000002,
+	}
+
+	citizen = &citizens.Citizen{
+		Id:        "user-1",
+		Email:     "ada@example.com",
+		FullName:  "Ada Lovelace",
+		Token:     "citizen-token",
+		CreatedAt: 1550000003,
+		UpdatedAt: 1550000004,
+	}
+
+	assistant = &assistants.Assistant{
+		Id:        "assistant-1",
+		Speaker:   "user-1",
+		Assistant: "user-2",
+		TalkId:    "talk-1",
+		Status:    types.StatusWaitlisted,
+		CreatedAt: 1550000005,
+		UpdatedAt: 1550000006,
+	}
+
+	session = &auth.Session{
+		Id:        "session-1",
+		UserId:    "user-1",
+		Email:     "ada@example.com",
+		FullName:  "Ada Lovelace",
+		Token:     "session-token",
+		CreatedAt: 1550000007,
+		UpdatedAt: 1550000008,
+	}
+
+	entry = &graph.AuditEntry{
+		Time:      time.Date(2019, 3, 14, 18, 0, 0, 0, time.UTC),
+		UserID:    "user-1",
+		Principal: "billing",
+		RequestID: "request-1",
+		Operation: "Cancel",
+		Mutation:  "cancelTalk",
+		Arguments: map[string]interface{}{"id": "talk-1"},
+		TargetIDs: []string{types.GlobalID("Talk", "talk-1")},
+		Outcome:   graph.AuditFailure,
+		ErrorCode: "NOT_FOUND",
+	}
+)
+
+// sources maps each object type of the schema to the message its fields
+// are resolved from. Queries and Mutations are resolved from the root.
+var sources = map[string]interface{}{
+	"Talk":         talk,
+	"User":         citizen,
+	"Assistant":    assistant,
+	"Session":      session,
+	"Registration": &types.RegistrationSource{Talk: talk, Assistant: assistant},
+	"AuditEntry":   entry,
+}
+
+// service marks the fields resolved by calling a backend instead of reading
+// the source message, only their resolver is checked.
+type service struct{}
+
+// mapping is the value every field resolves to from its source above.
+var mapping = map[string]interface{}{
+	"Talk.id":                 types.GlobalID("Talk", "talk-1"),
+	"Talk.title":              "Intro to gRPC",
+	"Talk.description":        "Services over HTTP/2",
+	"Talk.repository":         "https://github.com/go-toschool/grpc",
+	"Talk.date":               int64(1552586400),
+	"Talk.tags":               []string{"go", "grpc"},
+	"Talk.capacity":           int64(30),
+	"Talk.cancelled":          true,
+	"Talk.cancel_reason":      "Speaker is sick",
+	"Talk.created_at":         int64(1550000001),
+	"Talk.updated_at":         int64(1550000002),
+	"Talk.speaker":            service{},
+	"Talk.attendees":          service{},
+	"Talk.attendeeCount":      service{},
+	"Talk.waitlist":           service{},
+	"Talk.viewerRegistration": service{},
+
+	"User.id":         types.GlobalID("User", "user-1"),
+	"User.email":      "ada@example.com",
+	"User.full_name":  "Ada Lovelace",
+	"User.token":      "citizen-token",
+	"User.created_at": int64(1550000003),
+	"User.updated_at": int64(1550000004),
+	"User.talks":      service{},
+
+	"Assistant.id":                 types.GlobalID("Assistant", "assistant-1"),
+	"Assistant.talk_id":            types.GlobalID("Talk", "talk-1"),
+	"Assistant.user_id":            types.GlobalID("User", "user-2"),
+	"Assistant.speaker":            types.GlobalID("User", "user-1"),
+	"Assistant.registrationStatus": types.StatusWaitlisted,
+	"Assistant.created_at":         int64(1550000005),
+	"Assistant.updated_at":         int64(1550000006),
+
+	"Session.id":         types.GlobalID("Session", "session-1"),
+	"Session.user_id":    types.GlobalID("User", "user-1"),
+	"Session.email":      "ada@example.com",
+	"Session.fullname":   "Ada Lovelace",
+	"Session.token":      "session-token",
+	"Session.created_at": int64(1550000007),
+	"Session.updated_at": int64(1550000008),
+
+	"Registration.talk":      talk,
+	"Registration.assistant": assistant,
+
+	"AuditEntry.time":       time.Date(2019, 3, 14, 18, 0, 0, 0, time.UTC),
+	"AuditEntry.user_id":    "user-1",
+	"AuditEntry.principal":  "billing",
+	"AuditEntry.request_id": "request-1",
+	"AuditEntry.operation":  "Cancel",
+	"AuditEntry.mutation":   "cancelTalk",
+	"AuditEntry.arguments":  `{"id":"talk-1"}`,
+	"AuditEntry.target_ids": []string{types.GlobalID("Talk", "talk-1")},
+	"AuditEntry.outcome":    graph.AuditFailure,
+	"AuditEntry.error_code": "NOT_FOUND",
+}
+
+// TestMapping walks every object type of the schema and checks each field
+// resolves from the protobuf message of its type to the mapped value, and
+// rejects any other source instead of resolving to null.
+func TestMapping(t *testing.T) {
+	gctx := mock.New(&mock.Fixtures{
+		Citizens:   []*citizens.Citizen{citizen},
+		Talks:      []*talks.Talk{talk},
+		Assistants: []*assistants.Assistant{assistant},
+	}).Context()
+	s, err := schema.New(gctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := graph.NewContext(context.Background(), gctx)
+	ctx = graph.WithUserLoader(ctx, graph.NewUserLoader(gctx.UserService))
+	ctx = graph.WithAssistantsLoader(ctx, graph.NewAssistantsLoader(gctx.AssistantsService))
+	ctx = context.WithValue(ctx, sicily.UserIDKey, "user-1")
+
+	seen := make(map[string]bool)
+	for name, typ := range s.TypeMap() {
+		o, ok := typ.(*graphql.Object)
+		if !ok || strings.HasPrefix(name, "__") || o == s.QueryType() || o == s.MutationType() {
+			continue
+		}
+
+		source, ok := sources[name]
+		if !ok {
+			t.Errorf("%s: no protobuf message mapped", name)
+			continue
+		}
+
+		for fieldName, f := range o.Fields() {
+			key := name + "." + fieldName
+			seen[key] = true
+
+			want, ok := mapping[key]
+			if !ok {
+				t.Errorf("%s: not mapped", key)
+				continue
+			}
+			if f.Resolve == nil {
+				t.Errorf("%s: no resolver", key)
+				continue
+			}
+
+			p := graphql.ResolveParams{
+				Source:  source,
+				Context: ctx,
+				Info:    graphql.ResolveInfo{FieldName: fieldName, ParentType: o, ReturnType: f.Type},
+			}
+			got, err := f.Resolve(p)
+			if thunk, ok := got.(func() (interface{}, error)); ok && err == nil {
+				got, err = thunk()
+			}
+			if err != nil {
+				t.Errorf("%s: %v", key, err)
+				continue
+			}
+			if _, ok := want.(service); !ok && !reflect.DeepEqual(got, want) {
+				t.Errorf("%s = %#v, want %#v", key, got, want)
+			}
+
+			p.Source = struct{}{}
+			if _, err := f.Resolve(p); err == nil {
+				t.Errorf("%s: resolved from %T", key, p.Source)
+			}
+		}
+	}
+
+	for key := range mapping {
+		if !seen[key] {
+			t.Errorf("%s: mapped but not in the schema", key)
+		}
+	}
+}
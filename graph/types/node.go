@@ -0,0 +1,56 @@
+package types
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Node is implemented by every object that can be refetched by its global
+// id through the node query.
+var Node = graphql.NewInterface(graphql.InterfaceConfig{
+	Name:        "Node",
+	Description: "An object with a globally unique id",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Globally unique opaque identifier",
+		},
+	},
+})
+
+// ErrInvalidGlobalID is returned when an id is not a global id.
+var ErrInvalidGlobalID = errors.New("Invalid global id")
+
+// GlobalID returns the opaque global id of the object of type typeName
+// identified by id in its service.
+func GlobalID(typeName, id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(typeName + ":" + id))
+}
+
+// FromGlobalID returns the type name and service id encoded in globalID.
+func FromGlobalID(globalID string) (typeName, id string, err error) {
+	b, err := base64.StdEncoding.DecodeString(globalID)
+	if err != nil {
+		return "", "", ErrInvalidGlobalID
+	}
+
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrInvalidGlobalID
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// LocalID returns the service id of an object of type typeName given either
+// its global id or its service id, so arguments accept both.
+func LocalID(typeName, id string) string {
+	t, local, err := FromGlobalID(id)
+	if err != nil || t != typeName {
+		return id
+	}
+	return local
+}
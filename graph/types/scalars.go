@@ -0,0 +1,113 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// DateTime is an RFC 3339 date. The services store dates as Unix seconds,
+// so both time.Time and Unix seconds are serialized, and inputs accept Unix
+// seconds as an Int as a fallback for clients still sending timestamps.
+var DateTime = graphql.NewScalar(graphql.ScalarConfig{
+	Name: "DateTime",
+	Description: "An RFC 3339 date, e.g. \"2019-03-14T18:00:00Z\". " +
+		"Inputs also accept Unix timestamps in seconds as an Int.",
+	Serialize:  serializeDateTime,
+	ParseValue: parseDateTime,
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		switch v := valueAST.(type) {
+		case *ast.StringValue:
+			return parseDateTime(v.Value)
+		case *ast.IntValue:
+			seconds, err := strconv.ParseInt(v.Value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			return time.Unix(seconds, 0).UTC()
+		}
+		return nil
+	},
+})
+
+func serializeDateTime(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+		return v.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return serializeDateTime(*v)
+	case int64:
+		if v == 0 {
+			return nil
+		}
+		return serializeDateTime(time.Unix(v, 0))
+	case int:
+		return serializeDateTime(int64(v))
+	case int32:
+		return serializeDateTime(int64(v))
+	}
+
+	return nil
+}
+
+func parseDateTime(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil
+		}
+		return t
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return parseDateTime(*v)
+	case float64:
+		return time.Unix(int64(v), 0).UTC()
+	case int:
+		return time.Unix(int64(v), 0).UTC()
+	case int64:
+		return time.Unix(v, 0).UTC()
+	}
+
+	return nil
+}
+
+// SplitTags turns the comma separated tags stored by platon into a list.
+func SplitTags(tags string) []string {
+	list := make([]string, 0)
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			list = append(list, tag)
+		}
+	}
+
+	return list
+}
+
+// JoinTags turns a list of tags given as argument into the comma separated
+// form stored by platon.
+func JoinTags(value interface{}) string {
+	list, _ := value.([]interface{})
+
+	tags := make([]string, 0, len(list))
+	for _, v := range list {
+		tag, _ := v.(string)
+		tag = strings.TrimSpace(strings.Replace(tag, ",", " ", -1))
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return strings.Join(tags, ",")
+}
@@ -1,32 +1,54 @@
 package types
 
 import (
+	"github.com/go-toschool/palermo/auth"
 	"github.com/graphql-go/graphql"
 )
 
+// Session is a palermo session.
 var Session = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Session",
+	Name:        "Session",
+	Description: "An authenticated session",
+	Interfaces:  []*graphql.Interface{Node},
+	IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+		_, ok := p.Value.(*auth.Session)
+		return ok
+	},
 	Fields: graphql.Fields{
 		"id": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Globally unique session identifier",
+			Resolve:     sessionField(func(s *auth.Session) interface{} { return GlobalID("Session", s.GetId()) }),
 		},
 		"user_id": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Owner of the session",
+			Resolve:     sessionField(func(s *auth.Session) interface{} { return GlobalID("User", s.GetUserId()) }),
 		},
 		"email": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.String,
+			Description: "Email address of the owner",
+			Resolve:     sessionField(func(s *auth.Session) interface{} { return s.GetEmail() }),
 		},
 		"fullname": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.String,
+			Description: "Name of the owner",
+			Resolve:     sessionField(func(s *auth.Session) interface{} { return s.GetFullName() }),
 		},
 		"token": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.String,
+			Description: "Session token",
+			Resolve:     sessionField(func(s *auth.Session) interface{} { return s.GetToken() }),
 		},
 		"created_at": &graphql.Field{
-			Type: graphql.Int,
+			Type:        DateTime,
+			Description: "When the session was opened",
+			Resolve:     sessionField(func(s *auth.Session) interface{} { return s.GetCreatedAt() }),
 		},
 		"updated_at": &graphql.Field{
-			Type: graphql.Int,
+			Type:        DateTime,
+			Description: "When the session was last used",
+			Resolve:     sessionField(func(s *auth.Session) interface{} { return s.GetUpdatedAt() }),
 		},
 	},
 })
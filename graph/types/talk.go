@@ -1,35 +1,231 @@
 package types
 
 import (
+	"errors"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily"
+	"github.com/go-toschool/sicily/graph"
 	"github.com/graphql-go/graphql"
 )
 
+// Talk is a talk stored in platon.
 var Talk = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Talk",
+	Name:        "Talk",
+	Description: "A talk given by a speaker",
+	Interfaces:  []*graphql.Interface{Node},
+	IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+		_, ok := p.Value.(*talks.Talk)
+		return ok
+	},
 	Fields: graphql.Fields{
 		"id": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Globally unique talk identifier",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return GlobalID("Talk", t.GetId()) }),
 		},
 		"title": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Title of the talk",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetTitle() }),
 		},
 		"description": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.String,
+			Description: "Abstract of the talk",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetDescription() }),
 		},
 		"repository": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.String,
+			Description: "URL of the repository with the talk material",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetRepository() }),
 		},
 		"date": &graphql.Field{
-			Type: graphql.Int,
+			Type:        DateTime,
+			Description: "When the talk takes place",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetDate() }),
 		},
 		"tags": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Description: "Topics of the talk",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return SplitTags(t.GetTags()) }),
+		},
+		"capacity": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "Number of seats, 0 means unlimited",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetCapacity() }),
+		},
+		"cancelled": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Whether the speaker cancelled the talk",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetCancelled() }),
+		},
+		"cancel_reason": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Why the talk was cancelled",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetCancelReason() }),
 		},
 		"created_at": &graphql.Field{
-			Type: graphql.Int,
+			Type:        DateTime,
+			Description: "When the talk was created",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetCreatedAt() }),
 		},
 		"updated_at": &graphql.Field{
-			Type: graphql.Int,
+			Type:        DateTime,
+			Description: "When the talk was last changed",
+			Resolve:     talkField(func(t *talks.Talk) interface{} { return t.GetUpdatedAt() }),
+		},
+		"speaker": &graphql.Field{
+			Type:        onFailure(NullField, User),
+			Description: "User giving the talk, null when the user no longer exists or syracuse fails",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				t, ok := p.Source.(*talks.Talk)
+				if !ok {
+					return nil, sourceError("Talk", p)
+				}
+				if t.GetUserId() == "" {
+					return nil, nil
+				}
+
+				l, ok := graph.UserLoaderFromContext(p.Context)
+				if !ok {
+					return nil, errors.New("Missing user loader")
+				}
+				return l.Load(p.Context, t.GetUserId()), nil
+			},
+		},
+		"attendees": &graphql.Field{
+			Type:        onFailure(NullField, graphql.NewList(graphql.NewNonNull(Assistant))),
+			Description: "Users holding a seat in the talk, null when helenia fails",
+			Resolve: talkAttendees(func(aa []*assistants.Assistant) interface{} {
+				confirmed, _ := SplitAttendees(aa)
+				return confirmed
+			}),
+		},
+		"attendeeCount": &graphql.Field{
+			Type:        onFailure(NullField, graphql.Int),
+			Description: "Number of users holding a seat in the talk, null when helenia fails",
+			Resolve: talkAttendees(func(aa []*assistants.Assistant) interface{} {
+				confirmed, _ := SplitAttendees(aa)
+				return len(confirmed)
+			}),
+		},
+		"waitlist": &graphql.Field{
+			Type:        onFailure(NullField, graphql.NewList(graphql.NewNonNull(Assistant))),
+			Description: "Users waiting for a seat in promotion order, null when helenia fails",
+			Resolve: talkAttendees(func(aa []*assistants.Assistant) interface{} {
+				_, waitlist := SplitAttendees(aa)
+				return waitlist
+			}),
+		},
+		"viewerRegistration": &graphql.Field{
+			Type:        Assistant,
+			Description: "Registration of the authenticated user, null when not registered",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				userID, _ := p.Context.Value(sicily.UserIDKey).(string)
+				if userID == "" {
+					return nil, nil
+				}
+
+				return talkAttendees(func(aa []*assistants.Assistant) interface{} {
+					for _, a := range aa {
+						if a.GetAssistant() == userID {
+							return a
+						}
+					}
+					return nil
+				})(p)
+			},
+		},
+	},
+})
+
+// talkAttendees returns a resolver applying get to the assistants of the
+// talk being resolved, loaded from helenia once per request and talk.
+func talkAttendees(get func([]*assistants.Assistant) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		t, ok := p.Source.(*talks.Talk)
+		if !ok {
+			return nil, sourceError("Talk", p)
+		}
+
+		l, ok := graph.AssistantsLoaderFromContext(p.Context)
+		if !ok {
+			return nil, errors.New("Missing assistants loader")
+		}
+
+		load := l.Load(p.Context, t.GetId())
+		return func() (interface{}, error) {
+			aa, err := load()
+			if err != nil {
+				return nil, err
+			}
+			return get(aa), nil
+		}, nil
+	}
+}
+
+// CreateTalkInput holds the fields of a new talk.
+var CreateTalkInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "CreateTalkInput",
+	Description: "Fields of a new talk, the authenticated user is its speaker",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Title of the talk",
+		},
+		"description": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "Abstract of the talk",
+		},
+		"repository": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "URL of the repository with the talk material",
+		},
+		"date": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewNonNull(DateTime),
+			Description: "When the talk takes place",
+		},
+		"tags": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+			Description: "Topics of the talk",
+		},
+		"capacity": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Int,
+			Description: "Number of seats, omit for unlimited",
+		},
+	},
+})
+
+// TalkPatch holds the talk fields to change, omitted fields are kept and empty
+// strings or lists clear a field.
+var TalkPatch = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "TalkPatch",
+	Description: "Talk fields to change, omitted fields are kept and empty strings or lists clear a field",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "Title of the talk",
+		},
+		"description": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "Abstract of the talk",
+		},
+		"repository": &graphql.InputObjectFieldConfig{
+			Type:        graphql.String,
+			Description: "URL of the repository with the talk material",
+		},
+		"date": &graphql.InputObjectFieldConfig{
+			Type:        DateTime,
+			Description: "When the talk takes place",
+		},
+		"tags": &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+			Description: "Topics of the talk, replacing the current ones",
+		},
+		"capacity": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Int,
+			Description: "Number of seats, 0 for unlimited",
 		},
 	},
 })
@@ -1,42 +1,82 @@
 package types
 
 import (
+	"errors"
+
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/syracuse/citizens"
 	"github.com/graphql-go/graphql"
 )
 
+// User is a citizen stored in syracuse.
 var User = graphql.NewObject(graphql.ObjectConfig{
-	Name: "User",
+	Name:        "User",
+	Description: "A registered user",
+	Interfaces:  []*graphql.Interface{Node},
+	IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+		_, ok := p.Value.(*citizens.Citizen)
+		return ok
+	},
 	Fields: graphql.Fields{
 		"id": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Globally unique user identifier",
+			Resolve:     citizenField(func(c *citizens.Citizen) interface{} { return GlobalID("User", c.GetId()) }),
 		},
 		"email": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Email address",
+			Resolve:     citizenField(func(c *citizens.Citizen) interface{} { return c.GetEmail() }),
 		},
 		"full_name": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.String,
+			Description: "Name shown to other users",
+			Resolve:     citizenField(func(c *citizens.Citizen) interface{} { return c.GetFullName() }),
 		},
 		"token": &graphql.Field{
-			Type: graphql.String,
+			Type:        graphql.String,
+			Description: "Token of the user",
+			Resolve:     citizenField(func(c *citizens.Citizen) interface{} { return c.GetToken() }),
 		},
 		"created_at": &graphql.Field{
-			Type: graphql.Int,
+			Type:        DateTime,
+			Description: "When the user signed up",
+			Resolve:     citizenField(func(c *citizens.Citizen) interface{} { return c.GetCreatedAt() }),
 		},
 		"updated_at": &graphql.Field{
-			Type: graphql.Int,
+			Type:        DateTime,
+			Description: "When the user was last changed",
+			Resolve:     citizenField(func(c *citizens.Citizen) interface{} { return c.GetUpdatedAt() }),
 		},
 	},
 })
 
-// UserWithTalks this store user information and it subscribed talks.
-var UserWithTalks = graphql.NewObject(graphql.ObjectConfig{
-	Name: "UserWithTalks",
-	Fields: graphql.Fields{
-		"user": &graphql.Field{
-			Type: User,
-		},
-		"talks": &graphql.Field{
-			Type: graphql.NewList(Talk),
+func init() {
+	// talks is added once Talk exists, Talk refers to User through speaker.
+	User.AddFieldConfig("talks", &graphql.Field{
+		Type:        onFailure(NullField, graphql.NewList(graphql.NewNonNull(Talk))),
+		Description: "Talks given by the user, null when platon fails",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			c, ok := p.Source.(*citizens.Citizen)
+			if !ok {
+				return nil, sourceError("User", p)
+			}
+
+			ctx, ok := graph.FromContext(p.Context)
+			if !ok {
+				return nil, errors.New("Missing graph context")
+			}
+
+			ctxb := p.Context
+			tt, err := ctx.TalkService.Select(ctxb, &talks.SelectRequest{
+				UserId: c.GetId(),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return tt.GetTalk(), nil
 		},
-	},
-})
+	})
+}
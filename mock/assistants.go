@@ -0,0 +1,184 @@
+package mock
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/go-toschool/helenia/assistants"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Statuses of an assistant in helenia, an assistant without status holds a
+// seat.
+const (
+	statusConfirmed  = "confirmed"
+	statusWaitlisted = "waitlisted"
+)
+
+// Assistants is an in-memory assistants.AssistantsClient.
+type Assistants struct {
+	mu   sync.RWMutex
+	data map[string]*assistants.Assistant
+	ids  *ids
+}
+
+func newAssistants(seed []*assistants.Assistant) *Assistants {
+	s := &Assistants{
+		data: make(map[string]*assistants.Assistant),
+		ids:  &ids{prefix: "assistant"},
+	}
+	for _, a := range seed {
+		if a.Id != "" {
+			s.data[a.Id] = copyAssistant(a)
+		}
+	}
+	for _, a := range seed {
+		if a.Id == "" {
+			a = copyAssistant(a)
+			a.Id = s.ids.new(s.taken)
+			s.data[a.Id] = a
+		}
+	}
+
+	return s
+}
+
+// Get returns an assistant by id.
+func (s *Assistants) Get(ctx context.Context, in *assistants.GetRequest, opts ...grpc.CallOption) (*assistants.GetResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, ok := s.data[in.GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "assistant %q not found", in.GetId())
+	}
+
+	return &assistants.GetResponse{Data: copyAssistant(a)}, nil
+}
+
+// Select returns assistants in creation order, optionally filtered by talk
+// and user.
+func (s *Assistants) Select(ctx context.Context, in *assistants.SelectRequest, opts ...grpc.CallOption) (*assistants.SelectResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data := make([]*assistants.Assistant, 0)
+	for _, a := range s.data {
+		if in.GetTalkId() != "" && a.TalkId != in.GetTalkId() {
+			continue
+		}
+		if in.GetAssistant() != "" && a.Assistant != in.GetAssistant() {
+			continue
+		}
+		data = append(data, copyAssistant(a))
+	}
+	sort.Slice(data, func(i, j int) bool {
+		if data[i].CreatedAt != data[j].CreatedAt {
+			return data[i].CreatedAt < data[j].CreatedAt
+		}
+		return data[i].Id < data[j].Id
+	})
+
+	return &assistants.SelectResponse{Data: data}, nil
+}
+
+// Create stores a new assistant. With a capacity the assistant holds a seat
+// while the talk has free ones and is waitlisted otherwise, like helenia
+// the seats are counted and taken atomically. A user registers once per
+// talk, the second registration fails with AlreadyExists.
+func (s *Assistants) Create(ctx context.Context, in *assistants.CreateRequest, opts ...grpc.CallOption) (*assistants.CreateResponse, error) {
+	if in.GetData() == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing assistant")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := copyAssistant(in.GetData())
+	for _, b := range s.data {
+		if b.TalkId == a.TalkId && b.Assistant == a.Assistant {
+			return nil, status.Errorf(codes.AlreadyExists, "%q is already registered to talk %q", a.Assistant, a.TalkId)
+		}
+	}
+	if c := in.GetCapacity(); c > 0 {
+		a.Status = statusConfirmed
+		if s.confirmed(a.TalkId) >= c {
+			a.Status = statusWaitlisted
+		}
+	}
+	a.Id = s.ids.new(s.taken)
+	a.CreatedAt = now()
+	a.UpdatedAt = a.CreatedAt
+	s.data[a.Id] = a
+
+	return &assistants.CreateResponse{Data: copyAssistant(a)}, nil
+}
+
+// Update changes the status of an assistant. With a capacity, giving the
+// assistant a seat fails with FailedPrecondition when the talk has none left.
+func (s *Assistants) Update(ctx context.Context, in *assistants.UpdateRequest, opts ...grpc.CallOption) (*assistants.UpdateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.data[in.GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "assistant %q not found", in.GetId())
+	}
+	st := in.GetData().GetStatus()
+	if c := in.GetCapacity(); c > 0 && st == statusConfirmed && a.Status == statusWaitlisted && s.confirmed(a.TalkId) >= c {
+		return nil, status.Errorf(codes.FailedPrecondition, "talk %q is full", a.TalkId)
+	}
+	if st != "" {
+		a.Status = st
+	}
+	a.UpdatedAt = now()
+
+	return &assistants.UpdateResponse{Data: copyAssistant(a)}, nil
+}
+
+// Delete removes an assistant.
+func (s *Assistants) Delete(ctx context.Context, in *assistants.DeleteRequest, opts ...grpc.CallOption) (*assistants.DeleteResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.data[in.GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "assistant %q not found", in.GetId())
+	}
+	delete(s.data, a.Id)
+
+	return &assistants.DeleteResponse{Data: a}, nil
+}
+
+// confirmed returns the number of assistants holding a seat in a talk, it
+// must be called holding the lock.
+func (s *Assistants) confirmed(talkID string) int64 {
+	var n int64
+	for _, a := range s.data {
+		if a.TalkId == talkID && a.Status != statusWaitlisted {
+			n++
+		}
+	}
+	return n
+}
+
+// taken must be called holding the lock.
+func (s *Assistants) taken(id string) bool {
+	_, ok := s.data[id]
+	return ok
+}
+
+func copyAssistant(a *assistants.Assistant) *assistants.Assistant {
+	return &assistants.Assistant{
+		Id:        a.Id,
+		Speaker:   a.Speaker,
+		Assistant: a.Assistant,
+		TalkId:    a.TalkId,
+		Status:    a.Status,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
@@ -0,0 +1,142 @@
+package mock
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/go-toschool/syracuse/citizens"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Citizens is an in-memory citizens.CitizenshipClient.
+type Citizens struct {
+	mu   sync.RWMutex
+	data map[string]*citizens.Citizen
+	ids  *ids
+}
+
+func newCitizens(seed []*citizens.Citizen) *Citizens {
+	s := &Citizens{
+		data: make(map[string]*citizens.Citizen),
+		ids:  &ids{prefix: "citizen"},
+	}
+	for _, c := range seed {
+		if c.Id != "" {
+			s.data[c.Id] = copyCitizen(c)
+		}
+	}
+	for _, c := range seed {
+		if c.Id == "" {
+			c = copyCitizen(c)
+			c.Id = s.ids.new(s.taken)
+			s.data[c.Id] = c
+		}
+	}
+
+	return s
+}
+
+// Get returns a citizen by id or email.
+func (s *Citizens) Get(ctx context.Context, in *citizens.GetRequest, opts ...grpc.CallOption) (*citizens.GetResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if c, ok := s.data[in.GetUserId()]; ok {
+		return &citizens.GetResponse{Data: copyCitizen(c)}, nil
+	}
+	if in.GetEmail() != "" {
+		for _, c := range s.data {
+			if c.Email == in.GetEmail() {
+				return &citizens.GetResponse{Data: copyCitizen(c)}, nil
+			}
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "citizen %q not found", in.GetUserId())
+}
+
+// Select returns every citizen ordered by id.
+func (s *Citizens) Select(ctx context.Context, in *citizens.SelectRequest, opts ...grpc.CallOption) (*citizens.SelectResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data := make([]*citizens.Citizen, 0, len(s.data))
+	for _, c := range s.data {
+		data = append(data, copyCitizen(c))
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].Id < data[j].Id })
+
+	return &citizens.SelectResponse{Data: data}, nil
+}
+
+// Create stores a new citizen.
+func (s *Citizens) Create(ctx context.Context, in *citizens.CreateRequest, opts ...grpc.CallOption) (*citizens.CreateResponse, error) {
+	if in.GetData() == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing citizen")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := copyCitizen(in.GetData())
+	c.Id = s.ids.new(s.taken)
+	c.CreatedAt = now()
+	c.UpdatedAt = c.CreatedAt
+	s.data[c.Id] = c
+
+	return &citizens.CreateResponse{Data: copyCitizen(c)}, nil
+}
+
+// Update changes the non empty fields of a citizen.
+func (s *Citizens) Update(ctx context.Context, in *citizens.UpdateRequest, opts ...grpc.CallOption) (*citizens.UpdateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.data[in.GetUserId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "citizen %q not found", in.GetUserId())
+	}
+	if email := in.GetData().GetEmail(); email != "" {
+		c.Email = email
+	}
+	if fullName := in.GetData().GetFullName(); fullName != "" {
+		c.FullName = fullName
+	}
+	c.UpdatedAt = now()
+
+	return &citizens.UpdateResponse{Data: copyCitizen(c)}, nil
+}
+
+// Delete removes a citizen.
+func (s *Citizens) Delete(ctx context.Context, in *citizens.DeleteRequest, opts ...grpc.CallOption) (*citizens.DeleteResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.data[in.GetUserId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "citizen %q not found", in.GetUserId())
+	}
+	delete(s.data, c.Id)
+
+	return &citizens.DeleteResponse{Data: c}, nil
+}
+
+// taken must be called holding the lock.
+func (s *Citizens) taken(id string) bool {
+	_, ok := s.data[id]
+	return ok
+}
+
+func copyCitizen(c *citizens.Citizen) *citizens.Citizen {
+	return &citizens.Citizen{
+		Id:        c.Id,
+		Email:     c.Email,
+		FullName:  c.FullName,
+		Token:     c.Token,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
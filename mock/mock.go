@@ -0,0 +1,111 @@
+// Package mock implements the gRPC clients used by the gateway in memory, so
+// the GraphQL API can run without syracuse, platon, helenia and palermo.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-toschool/helenia/assistants"
+	"github.com/go-toschool/platon/talks"
+	"github.com/go-toschool/sicily/graph"
+	"github.com/go-toschool/syracuse/citizens"
+)
+
+// Session is a palermo session accepted by the mock auth service.
+type Session struct {
+	ID              string `json:"id"`
+	UserID          string `json:"user_id"`
+	AuthToken       string `json:"auth_token"`
+	ValidationToken string `json:"validation_token"`
+}
+
+// Fixtures is the seed data of the mock backends.
+type Fixtures struct {
+	Citizens   []*citizens.Citizen     `json:"citizens"`
+	Talks      []*talks.Talk           `json:"talks"`
+	Assistants []*assistants.Assistant `json:"assistants"`
+	Sessions   []*Session              `json:"sessions"`
+}
+
+// Backends groups the in-memory services.
+type Backends struct {
+	Citizens   *Citizens
+	Talks      *Talks
+	Assistants *Assistants
+	Sessions   *Sessions
+}
+
+// New creates backends seeded with f, f may be nil.
+func New(f *Fixtures) *Backends {
+	if f == nil {
+		f = &Fixtures{}
+	}
+
+	return &Backends{
+		Citizens:   newCitizens(f.Citizens),
+		Talks:      newTalks(f.Talks),
+		Assistants: newAssistants(f.Assistants),
+		Sessions:   newSessions(f.Sessions),
+	}
+}
+
+// Load creates backends seeded from a JSON fixture file, an empty path
+// creates empty backends.
+func Load(path string) (*Backends, error) {
+	if path == "" {
+		return New(nil), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("mock: %v", err)
+	}
+	defer file.Close()
+
+	f := &Fixtures{}
+	if err := json.NewDecoder(file).Decode(f); err != nil {
+		return nil, fmt.Errorf("mock: decode %s: %v", path, err)
+	}
+
+	return New(f), nil
+}
+
+// Context returns a graph context backed by the in-memory services.
+func (b *Backends) Context() *graph.Context {
+	return &graph.Context{
+		UserService:       b.Citizens,
+		TalkService:       b.Talks,
+		AssistantsService: b.Assistants,
+		SessionService:    b.Sessions,
+	}
+}
+
+// ids generates sequential identifiers with a prefix.
+type ids struct {
+	mu     sync.Mutex
+	prefix string
+	next   int
+}
+
+// new returns the next identifier for which taken reports false, so
+// generated ids never collide with the ones seeded from fixtures.
+func (i *ids) new(taken func(id string) bool) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for {
+		i.next++
+		id := fmt.Sprintf("%s-%d", i.prefix, i.next)
+		if !taken(id) {
+			return id
+		}
+	}
+}
+
+func now() int64 {
+	return time.Now().Unix()
+}
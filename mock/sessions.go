@@ -0,0 +1,133 @@
+package mock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-toschool/palermo/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sessions is an in-memory auth.AuthServiceClient.
+type Sessions struct {
+	mu   sync.RWMutex
+	data map[credentials]*Session
+	ids  *ids
+}
+
+type credentials struct {
+	authToken       string
+	validationToken string
+}
+
+func newSessions(seed []*Session) *Sessions {
+	s := &Sessions{
+		data: make(map[credentials]*Session),
+		ids:  &ids{prefix: "session"},
+	}
+	for _, sess := range seed {
+		s.data[credentials{sess.AuthToken, sess.ValidationToken}] = sess
+	}
+	for _, sess := range seed {
+		if sess.ID == "" {
+			sess.ID = s.ids.new(s.taken)
+		}
+	}
+
+	return s
+}
+
+// Get validates a pair of session credentials.
+func (s *Sessions) Get(ctx context.Context, in *auth.GetRequest, opts ...grpc.CallOption) (*auth.GetResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.data[credentialsOf(in.GetData())]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid session")
+	}
+
+	return &auth.GetResponse{Data: toSession(sess)}, nil
+}
+
+// Create opens a session for a user, the token of the request is used as
+// both auth and validation token.
+func (s *Sessions) Create(ctx context.Context, in *auth.CreateRequest, opts ...grpc.CallOption) (*auth.CreateResponse, error) {
+	if in.GetData().GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing user id")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess := &Session{
+		ID:              s.ids.new(s.taken),
+		UserID:          in.GetData().GetUserId(),
+		AuthToken:       in.GetData().GetToken(),
+		ValidationToken: in.GetData().GetToken(),
+	}
+	s.data[credentials{sess.AuthToken, sess.ValidationToken}] = sess
+
+	return &auth.CreateResponse{Data: toSession(sess)}, nil
+}
+
+// Delete closes a session.
+func (s *Sessions) Delete(ctx context.Context, in *auth.DeleteRequest, opts ...grpc.CallOption) (*auth.DeleteResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := credentialsOf(in.GetData())
+	sess, ok := s.data[key]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "session not found")
+	}
+	delete(s.data, key)
+
+	return &auth.DeleteResponse{Data: toSession(sess)}, nil
+}
+
+// For returns a session of a user, opening one if the user has none.
+func (s *Sessions) For(userID string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sess := range s.data {
+		if sess.UserID == userID {
+			return sess
+		}
+	}
+
+	sess := &Session{
+		ID:     s.ids.new(s.taken),
+		UserID: userID,
+	}
+	sess.AuthToken = sess.ID + "-auth"
+	sess.ValidationToken = sess.ID + "-validation"
+	s.data[credentials{sess.AuthToken, sess.ValidationToken}] = sess
+
+	return sess
+}
+
+// taken must be called holding the lock.
+func (s *Sessions) taken(id string) bool {
+	for _, sess := range s.data {
+		if sess.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func credentialsOf(c *auth.SessionCredentials) credentials {
+	return credentials{c.GetAuthToken(), c.GetValidationToken()}
+}
+
+func toSession(s *Session) *auth.Session {
+	return &auth.Session{
+		Id:     s.ID,
+		UserId: s.UserID,
+		Token:  s.AuthToken,
+	}
+}
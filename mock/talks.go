@@ -0,0 +1,221 @@
+package mock
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/go-toschool/platon/talks"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Talks is an in-memory talks.TalkingClient.
+type Talks struct {
+	mu   sync.RWMutex
+	data map[string]*talks.Talk
+	ids  *ids
+}
+
+func newTalks(seed []*talks.Talk) *Talks {
+	s := &Talks{
+		data: make(map[string]*talks.Talk),
+		ids:  &ids{prefix: "talk"},
+	}
+	for _, t := range seed {
+		if t.Id != "" {
+			s.data[t.Id] = copyTalk(t)
+		}
+	}
+	for _, t := range seed {
+		if t.Id == "" {
+			t = copyTalk(t)
+			t.Id = s.ids.new(s.taken)
+			s.data[t.Id] = t
+		}
+	}
+
+	return s
+}
+
+// Get returns a talk by id, or the first talk of a speaker.
+func (s *Talks) Get(ctx context.Context, in *talks.GetRequest, opts ...grpc.CallOption) (*talks.GetResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if in.GetTalkId() != "" {
+		t, ok := s.data[in.GetTalkId()]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "talk %q not found", in.GetTalkId())
+		}
+		return &talks.GetResponse{Talk: copyTalk(t)}, nil
+	}
+
+	for _, t := range s.sorted() {
+		if t.UserId == in.GetUserId() {
+			return &talks.GetResponse{Talk: copyTalk(t)}, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "no talks for user %q", in.GetUserId())
+}
+
+// Select returns every talk ordered by id, optionally filtered by speaker.
+func (s *Talks) Select(ctx context.Context, in *talks.SelectRequest, opts ...grpc.CallOption) (*talks.SelectResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data := make([]*talks.Talk, 0, len(s.data))
+	for _, t := range s.sorted() {
+		if in.GetUserId() != "" && t.UserId != in.GetUserId() {
+			continue
+		}
+		data = append(data, copyTalk(t))
+	}
+
+	return &talks.SelectResponse{Talk: data}, nil
+}
+
+// Create stores a new talk.
+func (s *Talks) Create(ctx context.Context, in *talks.CreateRequest, opts ...grpc.CallOption) (*talks.CreateResponse, error) {
+	if in.GetTalk() == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing talk")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := copyTalk(in.GetTalk())
+	t.Id = s.ids.new(s.taken)
+	t.CreatedAt = now()
+	t.UpdatedAt = t.CreatedAt
+	s.data[t.Id] = t
+
+	return &talks.CreateResponse{Talk: copyTalk(t)}, nil
+}
+
+// Update changes the fields of a talk listed in the update mask, zero values
+// included, or its non empty fields without a mask.
+func (s *Talks) Update(ctx context.Context, in *talks.UpdateRequest, opts ...grpc.CallOption) (*talks.UpdateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.data[in.GetTalkId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "talk %q not found", in.GetTalkId())
+	}
+
+	patch := in.GetTalk()
+	if mask := in.GetUpdateMask(); mask != nil {
+		u := copyTalk(t)
+		for _, path := range mask.GetPaths() {
+			switch path {
+			case "title":
+				u.Title = patch.GetTitle()
+			case "description":
+				u.Description = patch.GetDescription()
+			case "repository":
+				u.Repository = patch.GetRepository()
+			case "date":
+				u.Date = patch.GetDate()
+			case "tags":
+				u.Tags = patch.GetTags()
+			case "capacity":
+				u.Capacity = patch.GetCapacity()
+			default:
+				return nil, status.Errorf(codes.InvalidArgument, "unknown field %q", path)
+			}
+		}
+		u.UpdatedAt = now()
+		s.data[u.Id] = u
+
+		return &talks.UpdateResponse{Talk: copyTalk(u)}, nil
+	}
+
+	if patch.GetTitle() != "" {
+		t.Title = patch.GetTitle()
+	}
+	if patch.GetDescription() != "" {
+		t.Description = patch.GetDescription()
+	}
+	if patch.GetRepository() != "" {
+		t.Repository = patch.GetRepository()
+	}
+	if patch.GetDate() != 0 {
+		t.Date = patch.GetDate()
+	}
+	if patch.GetTags() != "" {
+		t.Tags = patch.GetTags()
+	}
+	if patch.GetCapacity() != 0 {
+		t.Capacity = patch.GetCapacity()
+	}
+	t.UpdatedAt = now()
+
+	return &talks.UpdateResponse{Talk: copyTalk(t)}, nil
+}
+
+// Delete removes a talk.
+func (s *Talks) Delete(ctx context.Context, in *talks.DeleteRequest, opts ...grpc.CallOption) (*talks.DeleteResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.data[in.GetTalkId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "talk %q not found", in.GetTalkId())
+	}
+	delete(s.data, t.Id)
+
+	return &talks.DeleteResponse{Talk: t}, nil
+}
+
+// Cancel marks a talk as cancelled.
+func (s *Talks) Cancel(ctx context.Context, in *talks.CancelRequest, opts ...grpc.CallOption) (*talks.CancelResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.data[in.GetTalkId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "talk %q not found", in.GetTalkId())
+	}
+	t.Cancelled = true
+	t.CancelReason = in.GetReason()
+	t.UpdatedAt = now()
+
+	return &talks.CancelResponse{Talk: copyTalk(t)}, nil
+}
+
+// sorted must be called holding the lock.
+func (s *Talks) sorted() []*talks.Talk {
+	tt := make([]*talks.Talk, 0, len(s.data))
+	for _, t := range s.data {
+		tt = append(tt, t)
+	}
+	sort.Slice(tt, func(i, j int) bool { return tt[i].Id < tt[j].Id })
+
+	return tt
+}
+
+// taken must be called holding the lock.
+func (s *Talks) taken(id string) bool {
+	_, ok := s.data[id]
+	return ok
+}
+
+func copyTalk(t *talks.Talk) *talks.Talk {
+	return &talks.Talk{
+		Id:           t.Id,
+		Title:        t.Title,
+		Description:  t.Description,
+		Repository:   t.Repository,
+		Date:         t.Date,
+		Tags:         t.Tags,
+		UserId:       t.UserId,
+		Capacity:     t.Capacity,
+		Cancelled:    t.Cancelled,
+		CancelReason: t.CancelReason,
+		CreatedAt:    t.CreatedAt,
+		UpdatedAt:    t.UpdatedAt,
+	}
+}